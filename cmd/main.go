@@ -21,19 +21,26 @@ import (
 	"crypto/tls"
 	_ "embed"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"aedificans.com/k8s-ssm-param-injector/pkg/injector"
+	"aedificans.com/k8s-ssm-param-injector/pkg/provider"
 	"aedificans.com/k8s-ssm-param-injector/pkg/utils"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -56,18 +63,53 @@ func init() {
 	// +kubebuilder:scaffold:scheme
 }
 
+// fieldPathRules collects the FieldPathRules given by repeated --field-paths flags.
+type fieldPathRules []injector.FieldPathRule
+
+func (r *fieldPathRules) String() string {
+	return fmt.Sprint(*r)
+}
+
+func (r *fieldPathRules) Set(value string) error {
+	rule, err := injector.ParseFieldPathRule(value)
+	if err != nil {
+		return err
+	}
+	*r = append(*r, rule)
+	return nil
+}
+
 func main() {
 	var awsRegion string
+	var cacheMaxSize int
+	var cacheTTL time.Duration
 	var enableHTTP2 bool
 	var enableLeaderElection bool
+	var enableSecretRefs bool
+	var fieldPaths fieldPathRules
+	var fieldPathsConfig string
 	var metricsAddr string
 	var probeAddr string
+	var providerFile string
+	var providerName string
 	var secureMetrics bool
 	var webhookPort int
 	flag.StringVar(&awsRegion, "aws-region", utils.GetEnvString("AWS_REGION", "us-east-1"),
 		"The AWS region for the SSM client to create a session in for the service.")
+	flag.IntVar(&cacheMaxSize, "cache-max-size", utils.GetEnvInt("CACHE_MAX_SIZE", 1000),
+		"The maximum number of parameter values to hold in the cache.")
+	flag.DurationVar(&cacheTTL, "cache-ttl", utils.GetEnvDuration("CACHE_TTL", time.Minute),
+		"How long retrieved parameter values are cached for. Use 0 to disable the cache.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", utils.GetEnvBool("ENABLE_HTTP2", false),
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers.")
+	flag.BoolVar(&enableSecretRefs, "enable-secret-refs", utils.GetEnvBool("ENABLE_SECRET_REFS", false),
+		"If set, workloads can opt into injecting SecureString values through generated Secrets.")
+	flag.Var(&fieldPaths, "field-paths",
+		"A rule of the form <kind>[.<group>]=<field path>[,<field path>...] selecting the fields searched for"+
+			" SSM Parameter references in resources without a dedicated handler. May be repeated.")
+	flag.StringVar(&fieldPathsConfig, "field-paths-config", utils.GetEnvString("FIELD_PATHS_CONFIG", ""),
+		"The path of a YAML document of rules selecting the fields searched for SSM Parameter references"+
+			" in resources without a dedicated handler.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", utils.GetEnvString("HEALTH_PROBE_BIND_ADDRESS", ":8081"),
 		"The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", utils.GetEnvBool("LEADER_ELECT", false),
@@ -78,6 +120,10 @@ func main() {
 			" Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
 	flag.BoolVar(&secureMetrics, "metrics-secure", utils.GetEnvBool("METRICS_SECURE", true),
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
+	flag.StringVar(&providerName, "provider", utils.GetEnvString("PROVIDER", "ssm"),
+		"The provider to retrieve parameter values from. One of ssm or file.")
+	flag.StringVar(&providerFile, "provider-file", utils.GetEnvString("PROVIDER_FILE", "parameters.yaml"),
+		"The YAML document of parameter values read by the file provider, such as a mounted ConfigMap.")
 	flag.IntVar(&webhookPort, "webhook-address", utils.GetEnvInt("WEBHOOK_PORT", 8443),
 		"The port of the webhook server for the mutating webhook.")
 	opts := zap.Options{
@@ -98,6 +144,21 @@ func main() {
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)).
 		WithValues("app", "ssm-param-injector", "commit", Commit))
 
+	for _, rule := range strings.Fields(utils.GetEnvString("FIELD_PATHS", "")) {
+		if err := fieldPaths.Set(rule); err != nil {
+			setupLog.Error(err, "invalid field path rule in FIELD_PATHS")
+			os.Exit(1)
+		}
+	}
+	if fieldPathsConfig != "" {
+		rules, err := injector.LoadFieldPathRules(fieldPathsConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to load field paths config")
+			os.Exit(1)
+		}
+		fieldPaths = append(fieldPaths, rules...)
+	}
+
 	disableHTTP2 := func(c *tls.Config) {
 		setupLog.Info("disabling http/2")
 		c.NextProtos = []string{"http/1.1"}
@@ -116,19 +177,39 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "57458571.aedificans.com",
+		Cache: cache.Options{ByObject: map[client.Object]cache.ByObject{
+			// Only the generated Secrets are watched, rather than every Secret in the cluster.
+			&corev1.Secret{}: {Label: injector.GeneratedSecretSelector},
+		}},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	cfg, err := awsConfig.LoadDefaultConfig(context.TODO(), awsConfig.WithRegion(awsRegion))
-	if err != nil {
-		setupLog.Error(err, "failed to load aws config")
-		panic(err)
+	var parameterProvider provider.Provider
+	switch providerName {
+	case "ssm":
+		cfg, err := awsConfig.LoadDefaultConfig(context.TODO(), awsConfig.WithRegion(awsRegion))
+		if err != nil {
+			setupLog.Error(err, "failed to load aws config")
+			panic(err)
+		}
+		parameterProvider = &provider.SSMProvider{Client: ssm.NewFromConfig(cfg)}
+	case "file":
+		parameterProvider, err = provider.NewFileProvider(providerFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load parameters file")
+			os.Exit(1)
+		}
+	default:
+		setupLog.Error(nil, "unsupported parameter provider", "provider", providerName)
+		os.Exit(1)
 	}
 
-	ssmClient := ssm.NewFromConfig(cfg)
+	if cacheTTL > 0 {
+		parameterProvider = provider.NewCachingProvider(parameterProvider, cacheTTL, cacheMaxSize)
+	}
 
 	webhookServer := webhook.NewServer(webhook.Options{
 		CertDir: "ssl",
@@ -137,13 +218,26 @@ func main() {
 	})
 	webhookServer.Register("/mutate", &webhook.Admission{
 		Handler: &injector.SSMParameterInjector{
-			SsmClient: ssmClient,
-			Decoder:   admission.NewDecoder(scheme)}})
+			Provider:         parameterProvider,
+			Client:           mgr.GetClient(),
+			Decoder:          admission.NewDecoder(scheme),
+			FieldPathRules:   fieldPaths,
+			EnableSecretRefs: enableSecretRefs}})
 	if err := mgr.Add(webhookServer); err != nil {
 		setupLog.Error(err, "unable to Add webhook server")
 		os.Exit(1)
 	}
 
+	if enableSecretRefs {
+		if err := (&injector.GeneratedSecretReconciler{
+			Client:    mgr.GetClient(),
+			APIReader: mgr.GetAPIReader(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "generated-secret")
+			os.Exit(1)
+		}
+	}
+
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
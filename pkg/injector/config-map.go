@@ -20,8 +20,9 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"strings"
+	"unicode/utf8"
 
+	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -39,23 +40,59 @@ func (s *SSMParameterInjector) handleConfigMap(ctx context.Context, req admissio
 	log.Log.WithValues("name", configMap.Name, "namespace", configMap.Namespace).
 		V(1).Info("ConfigMap successfully decoded")
 
-	wasModified := false
-	if configMap.Data != nil {
-		for key, value := range configMap.Data {
-			if strings.HasPrefix(value, "ssm:/") {
-				log.Log.Info("SSM Parameter detected in ConfigMap data")
-				log.Log.WithValues("paramKey", value).
-					V(1).Info("SSM Parameter detected")
-				paramName := strings.TrimPrefix(value, "ssm:/")
-				paramValue, err := s.getSSMParameter(ctx, paramName)
-				if err != nil {
-					return admission.Errored(http.StatusInternalServerError, err)
-				}
-				log.Log.V(1).Info("Updating ConfigMap data with SSM Parameter value")
-				configMap.Data[key] = paramValue
-				wasModified = true
-			}
+	// The data of an immutable ConfigMap cannot change, so updates of one which was already
+	// immutable are left for the API server to validate, whereas a ConfigMap which is only now
+	// being made immutable is resolved as usual.
+	if req.Operation == admissionv1.Update {
+		oldConfigMap := &corev1.ConfigMap{}
+		if err := s.Decoder.DecodeRaw(req.OldObject, oldConfigMap); err != nil {
+			log.Log.Error(err, "unable to decode old ConfigMap")
+			return admission.Errored(http.StatusBadRequest, err)
 		}
+		if oldConfigMap.Immutable != nil && *oldConfigMap.Immutable {
+			log.Log.WithValues("name", configMap.Name, "namespace", configMap.Namespace).
+				Info("Skipping update of immutable ConfigMap, references are only resolved on creation")
+			return admission.Allowed("Immutable ConfigMap")
+		}
+	}
+
+	documentFormats, err := documentFormats(configMap.Annotations)
+	if err != nil {
+		log.Log.Error(err, "invalid "+documentKeysAnnotation+" annotation")
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	refs := &parameterReferences{}
+	collectObjectMeta(refs, &configMap.ObjectMeta)
+	for key, value := range configMap.Data {
+		write := func(paramValue string) {
+			configMap.Data[key] = paramValue
+		}
+		if format, ok := documentFormats[key]; ok {
+			collectDocument(refs, "ConfigMap data "+key, value, format, write)
+			continue
+		}
+		refs.add("ConfigMap data", value, write)
+	}
+	for key, value := range configMap.BinaryData {
+		// Binary values which are not UTF-8 text cannot contain references.
+		if !utf8.Valid(value) {
+			continue
+		}
+		write := func(paramValue string) {
+			configMap.BinaryData[key] = []byte(paramValue)
+		}
+		if format, ok := documentFormats[key]; ok {
+			collectDocument(refs, "ConfigMap binaryData "+key, string(value), format, write)
+			continue
+		}
+		refs.add("ConfigMap binaryData", string(value), write)
+	}
+	collectDataFromPath(refs, configMap)
+
+	wasModified, err := s.injectParameters(ctx, refs)
+	if err != nil {
+		return admission.Errored(errorCode(err), err)
 	}
 
 	if !wasModified {
@@ -70,5 +107,5 @@ func (s *SSMParameterInjector) handleConfigMap(ctx context.Context, req admissio
 	}
 
 	log.Log.Info("Returning JSON patch for value injection(s)")
-	return admission.PatchResponseFromRaw(req.Object.Raw, configMapJson)
+	return admission.PatchResponseFromRaw(req.Object.Raw, configMapJson).WithWarnings(refs.warnings...)
 }
@@ -0,0 +1,168 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"maps"
+	"testing"
+
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newConfigMap(immutable *bool, host string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Immutable:  immutable,
+		Data:       map[string]string{"host": host},
+	}
+}
+
+func TestHandleConfigMapImmutable(t *testing.T) {
+	s := newTestInjector(fakeProvider{"/app/db/host": {Value: "db.local"}})
+
+	tests := []struct {
+		name      string
+		operation admissionv1.Operation
+		old       *corev1.ConfigMap
+		updated   *corev1.ConfigMap
+		expected  string
+	}{
+		{
+			name:      "created immutable",
+			operation: admissionv1.Create,
+			updated:   newConfigMap(ptr.To(true), "ssm://app/db/host"),
+			expected:  "db.local",
+		},
+		{
+			name:      "made immutable",
+			operation: admissionv1.Update,
+			old:       newConfigMap(nil, "db.local"),
+			updated:   newConfigMap(ptr.To(true), "ssm://app/db/host"),
+			expected:  "db.local",
+		},
+		{
+			name:      "mutable",
+			operation: admissionv1.Update,
+			old:       newConfigMap(ptr.To(false), "db.local"),
+			updated:   newConfigMap(nil, "ssm://app/db/host"),
+			expected:  "db.local",
+		},
+		{
+			name:      "already immutable",
+			operation: admissionv1.Update,
+			old:       newConfigMap(ptr.To(true), "db.local"),
+			updated:   newConfigMap(ptr.To(true), "ssm://app/db/host"),
+			expected:  "ssm://app/db/host",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var old client.Object
+			if test.old != nil {
+				old = test.old
+			}
+			patched := &corev1.ConfigMap{}
+			resp := admitUpdate(t, s, test.operation, old, test.updated, patched)
+			if !resp.Allowed {
+				t.Fatalf("expected the ConfigMap to be allowed, got %v", resp.Result)
+			}
+			if host := patched.Data["host"]; host != test.expected {
+				t.Errorf("expected host %q, got %q", test.expected, host)
+			}
+		})
+	}
+}
+
+func TestDataFromPath(t *testing.T) {
+	s := newTestInjector(fakeProvider{
+		"/app/config/level":       {Value: "debug"},
+		"/app/config/db/host":     {Value: "db.local"},
+		"/app/config/db/port":     {Value: "5432"},
+		"/app/config/db/password": {Value: "hunter2", Type: ssmtypes.ParameterTypeSecureString},
+	})
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		data        map[string]string
+		expected    map[string]string
+		generated   string
+		warnings    int
+	}{
+		{
+			name:        "nested paths flattened",
+			annotations: map[string]string{dataFromPathAnnotation: "/app/config/"},
+			expected:    map[string]string{"level": "debug", "db.host": "db.local", "db.port": "5432"},
+			generated:   "db.host,db.port,level",
+			warnings:    1,
+		},
+		{
+			name:        "separator",
+			annotations: map[string]string{dataFromPathAnnotation: "/app/config", dataFromPathSeparatorAnnotation: "_"},
+			expected:    map[string]string{"level": "debug", "db_host": "db.local", "db_port": "5432"},
+			generated:   "db_host,db_port,level",
+			warnings:    1,
+		},
+		{
+			name:        "explicit keys take precedence",
+			annotations: map[string]string{dataFromPathAnnotation: "/app/config/db"},
+			data:        map[string]string{"host": "override.local"},
+			expected:    map[string]string{"host": "override.local", "port": "5432"},
+			generated:   "port",
+			warnings:    1,
+		},
+		{
+			name: "previously generated keys refreshed",
+			annotations: map[string]string{
+				dataFromPathAnnotation:          "/app/config/db",
+				dataFromPathGeneratedAnnotation: "host,port,user",
+			},
+			data:      map[string]string{"host": "old.local", "port": "3306", "user": "admin"},
+			expected:  map[string]string{"host": "db.local", "port": "5432"},
+			generated: "host,port",
+			warnings:  1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Annotations: test.annotations},
+				Data:       test.data,
+			}
+			patched := &corev1.ConfigMap{}
+			resp := admit(t, s, admissionv1.Create, configMap, patched)
+			if !resp.Allowed {
+				t.Fatalf("expected the ConfigMap to be allowed, got %v", resp.Result)
+			}
+			if !maps.Equal(patched.Data, test.expected) {
+				t.Errorf("expected data %v, got %v", test.expected, patched.Data)
+			}
+			if generated := patched.Annotations[dataFromPathGeneratedAnnotation]; generated != test.generated {
+				t.Errorf("expected generated keys %q, got %q", test.generated, generated)
+			}
+			// SecureString parameters are left out of the data with a warning.
+			if len(resp.Warnings) != test.warnings {
+				t.Errorf("expected %d warnings, got %q", test.warnings, resp.Warnings)
+			}
+		})
+	}
+}
@@ -38,23 +38,21 @@ func (s *SSMParameterInjector) handleCronJob(ctx context.Context, req admission.
 	log.Log.WithValues("name", cronJob.Name, "namespace", cronJob.Namespace).
 		V(1).Info("CronJob successfully decoded")
 
-	hasUpdatedContainers := false
-	if cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers != nil {
-		hasUpdatedContainers, err = s.processContainers(ctx, cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers)
-		if err != nil {
-			return admission.Errored(http.StatusInternalServerError, err)
-		}
+	podTemplate := &cronJob.Spec.JobTemplate.Spec.Template
+	refs := &parameterReferences{
+		secret: s.newGeneratedSecret(req, &cronJob.ObjectMeta, &podTemplate.ObjectMeta),
 	}
+	collectObjectMeta(refs, &cronJob.ObjectMeta)
+	collectObjectMeta(refs, &cronJob.Spec.JobTemplate.ObjectMeta)
+	collectLabelSelector(refs, cronJob.Spec.JobTemplate.Spec.Selector)
+	collectPodTemplate(refs, podTemplate)
 
-	hasUpdatedInitContainers := false
-	if cronJob.Spec.JobTemplate.Spec.Template.Spec.InitContainers != nil {
-		hasUpdatedInitContainers, err = s.processContainers(ctx, cronJob.Spec.JobTemplate.Spec.Template.Spec.InitContainers)
-		if err != nil {
-			return admission.Errored(http.StatusInternalServerError, err)
-		}
+	wasModified, err := s.injectParameters(ctx, refs)
+	if err != nil {
+		return admission.Errored(errorCode(err), err)
 	}
 
-	if !hasUpdatedContainers && !hasUpdatedInitContainers {
+	if !wasModified {
 		log.Log.Info("No SSM parameters found")
 		return admission.Allowed("No modifications required")
 	}
@@ -66,5 +64,5 @@ func (s *SSMParameterInjector) handleCronJob(ctx context.Context, req admission.
 	}
 
 	log.Log.Info("Returning JSON patch for value injection(s)")
-	return admission.PatchResponseFromRaw(req.Object.Raw, cronJobJson)
+	return admission.PatchResponseFromRaw(req.Object.Raw, cronJobJson).WithWarnings(refs.warnings...)
 }
@@ -0,0 +1,112 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"aedificans.com/k8s-ssm-param-injector/pkg/provider"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// dataFromPathAnnotation lists the comma-separated parameter paths whose parameters are
+	// injected as keys of a ConfigMap's data.
+	dataFromPathAnnotation = annotationPrefix + "data-from-path"
+	// dataFromPathSeparatorAnnotation replaces the "/" separating the names of nested paths
+	// in generated keys, which defaults to defaultDataFromPathSeparator.
+	dataFromPathSeparatorAnnotation = annotationPrefix + "data-from-path-separator"
+	// dataFromPathGeneratedAnnotation records the comma-separated generated keys, so that they
+	// are replaced when the ConfigMap is admitted again rather than kept as explicit keys.
+	dataFromPathGeneratedAnnotation = annotationPrefix + "data-from-path-generated"
+
+	defaultDataFromPathSeparator = "."
+)
+
+// collectDataFromPath registers the parameter paths of the dataFromPathAnnotation, if any, to
+// be injected as keys of the ConfigMap's data.
+func collectDataFromPath(refs *parameterReferences, configMap *corev1.ConfigMap) {
+	paths := splitList(configMap.Annotations[dataFromPathAnnotation])
+	if len(paths) == 0 {
+		return
+	}
+
+	separator, ok := configMap.Annotations[dataFromPathSeparatorAnnotation]
+	if !ok {
+		separator = defaultDataFromPathSeparator
+	}
+
+	refs.addPaths(dataFromPathAnnotation+" annotation", paths, func(pathValues map[string]map[string]provider.Parameter) {
+		// Later paths take precedence over earlier ones when they generate the same key.
+		dataValues := map[string]string{}
+		for _, path := range paths {
+			for paramName, value := range pathValues[path] {
+				// The data of a ConfigMap is not secret, so SecureString values are left out of it.
+				if value.IsSecure() {
+					refs.warnings = append(refs.warnings,
+						fmt.Sprintf("skipping SecureString SSM Parameter %s: ConfigMap data is not secret", paramName))
+					continue
+				}
+				key := dataKey(path, paramName, separator)
+				if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+					refs.warnings = append(refs.warnings, fmt.Sprintf("skipping SSM Parameter %s: invalid ConfigMap key %q: %s",
+						paramName, key, strings.Join(errs, ", ")))
+					continue
+				}
+				dataValues[key] = value.Value
+			}
+		}
+
+		previous := splitList(configMap.Annotations[dataFromPathGeneratedAnnotation])
+		for _, key := range previous {
+			delete(configMap.Data, key)
+		}
+
+		var generated []string
+		for key, value := range dataValues {
+			if _, explicit := configMap.Data[key]; explicit {
+				continue
+			}
+			if configMap.Data == nil {
+				configMap.Data = map[string]string{}
+			}
+			configMap.Data[key] = value
+			generated = append(generated, key)
+		}
+		sort.Strings(generated)
+
+		if len(generated) > 0 {
+			configMap.Annotations[dataFromPathGeneratedAnnotation] = strings.Join(generated, ",")
+		} else {
+			delete(configMap.Annotations, dataFromPathGeneratedAnnotation)
+		}
+	})
+}
+
+// dataKey derives a ConfigMap key from the parameter's name relative to the path, joining the
+// names of nested paths with the separator, so that "/app/prod/config/db/host" beneath
+// "/app/prod/config/" becomes "db.host" with the default separator.
+func dataKey(path string, paramName string, separator string) string {
+	segments := strings.Split(relativeParameterName(path, paramName), "/")
+	return strings.Join(slices.DeleteFunc(segments, func(segment string) bool {
+		return segment == ""
+	}), separator)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newLabelledDeployment(label string, image string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "web", "tier": label},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{label}},
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web", "tier": label}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Image: image}},
+				},
+			},
+		},
+	}
+}
+
+func TestHandleDeploymentSelector(t *testing.T) {
+	s := newTestInjector(fakeProvider{"/app/tier": {Value: "frontend"}})
+
+	patched := &appsv1.Deployment{}
+	resp := admit(t, s, admissionv1.Create, newLabelledDeployment("ssm://app/tier", "nginx"), patched)
+	if !resp.Allowed {
+		t.Fatalf("expected the Deployment to be allowed, got %v", resp.Result)
+	}
+
+	if tier := patched.Spec.Template.Labels["tier"]; tier != "frontend" {
+		t.Errorf("expected the template label to be resolved, got %q", tier)
+	}
+	if tier := patched.Spec.Selector.MatchLabels["tier"]; tier != "frontend" {
+		t.Errorf("expected the selector label to be resolved, got %q", tier)
+	}
+	if values := patched.Spec.Selector.MatchExpressions[0].Values; len(values) != 1 || values[0] != "frontend" {
+		t.Errorf("expected the selector expression to be resolved, got %q", values)
+	}
+}
+
+func TestHandleDeploymentInvalidValues(t *testing.T) {
+	s := newTestInjector(fakeProvider{
+		"/app/tier":  {Value: "not a label value"},
+		"/app/image": {Value: "Not An Image"},
+	})
+
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+	}{
+		{name: "label", deployment: newLabelledDeployment("ssm://app/tier", "nginx")},
+		{name: "image", deployment: newLabelledDeployment("frontend", "ssm://app/image")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := admit(t, s, admissionv1.Create, test.deployment, &appsv1.Deployment{})
+			if resp.Allowed || resp.Result.Code != http.StatusBadRequest {
+				t.Errorf("expected the Deployment to be rejected as a bad request, got %v", resp.Result)
+			}
+		})
+	}
+}
+
+func TestHandleDeploymentLastAppliedConfiguration(t *testing.T) {
+	s := newTestInjector(fakeProvider{"/app/password": {Value: `p"w`, Type: ssmtypes.ParameterTypeSecureString}})
+	s.EnableSecretRefs = true
+
+	// kubectl apply records the resource as applied, references and all, which must be kept as
+	// it is rather than leak the SecureString value and become invalid JSON.
+	deployment := newSecretRefDeployment()
+	deployment.Annotations["note"] = "${ssm://app/password}"
+	lastApplied, err := json.Marshal(deployment)
+	if err != nil {
+		t.Fatalf("unable to marshal the Deployment: %s", err)
+	}
+	deployment.Annotations[corev1.LastAppliedConfigAnnotation] = string(lastApplied)
+
+	patched := &appsv1.Deployment{}
+	resp := admit(t, s, admissionv1.Create, deployment, patched)
+	if !resp.Allowed {
+		t.Fatalf("expected the Deployment to be allowed, got %v", resp.Result)
+	}
+
+	if annotation := patched.Annotations[corev1.LastAppliedConfigAnnotation]; annotation != string(lastApplied) {
+		t.Errorf("expected the last applied configuration to be kept, got %s", annotation)
+	}
+	if note := patched.Annotations["note"]; note != `p"w` {
+		t.Errorf("expected other annotations to be resolved, got %q", note)
+	}
+	if env := patched.Spec.Template.Spec.Containers[0].Env[0]; env.Value != "" || env.ValueFrom == nil {
+		t.Errorf("expected the SecureString to be injected through a Secret, got %+v", env)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseDotenvDocument finds the values of a .env document's "KEY=value" lines, which may be
+// preceded by "export" and are unquoted, single quoted or double quoted. Each value is replaced
+// in place, keeping its quoting where it can represent the resolved value, so that comments and
+// the rest of the document are kept.
+func parseDotenvDocument(source string) (*document, error) {
+	spliced := &splicedDocument{source: source}
+	doc := &document{render: spliced.render}
+
+	for pos := 0; pos < len(source); {
+		lineEnd := lineEndIndex(source, pos)
+		line := strings.TrimLeft(source[pos:lineEnd], " \t")
+		lineStart := lineEnd - len(line)
+		if line == "" || line[0] == '#' {
+			pos = nextLineIndex(source, lineEnd)
+			continue
+		}
+
+		key, _, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !found {
+			pos = nextLineIndex(source, lineEnd)
+			continue
+		}
+		key = strings.TrimSpace(key)
+		start := lineStart + strings.Index(line, "=") + 1
+		for start < lineEnd && (source[start] == ' ' || source[start] == '\t') {
+			start++
+		}
+
+		var value string
+		var end int
+		var encode func(string) string
+		switch {
+		case start < lineEnd && source[start] == '\'':
+			closing := strings.IndexByte(source[start+1:], '\'')
+			if closing < 0 {
+				return nil, fmt.Errorf("unterminated single quoted value of %s", key)
+			}
+			end = start + 1 + closing + 1
+			value, encode = source[start+1:end-1], encodeSingleQuotedDotenv
+		case start < lineEnd && source[start] == '"':
+			end = -1
+			for i := start + 1; i < len(source); i++ {
+				if source[i] == '\\' {
+					i++
+				} else if source[i] == '"' {
+					end = i + 1
+					break
+				}
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated double quoted value of %s", key)
+			}
+			value, encode = unescapeDotenv(source[start+1:end-1]), encodeDoubleQuotedDotenv
+		default:
+			// An unquoted value ends at a comment, which must be preceded by whitespace.
+			end = lineEnd
+			for i := start; i < lineEnd; i++ {
+				if source[i] == '#' && i > start && (source[i-1] == ' ' || source[i-1] == '\t') {
+					end = i
+					break
+				}
+			}
+			value = strings.TrimRight(source[start:end], " \t")
+			end = start + len(value)
+			encode = encodeUnquotedDotenv
+		}
+
+		spliced.add(doc, key, value, start, end, encode)
+		pos = nextLineIndex(source, max(end, lineEnd))
+	}
+
+	return doc, nil
+}
+
+func unescapeDotenv(value string) string {
+	var unescaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			unescaped.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			unescaped.WriteByte('\n')
+		case '\\', '"', '$':
+			unescaped.WriteByte(value[i])
+		default:
+			unescaped.WriteByte('\\')
+			unescaped.WriteByte(value[i])
+		}
+	}
+	return unescaped.String()
+}
+
+func encodeDoubleQuotedDotenv(value string) string {
+	// "$" is escaped so that the resolved value is not expanded as a variable.
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`).Replace(value) + `"`
+}
+
+func encodeSingleQuotedDotenv(value string) string {
+	if strings.ContainsAny(value, "'\n") {
+		return encodeDoubleQuotedDotenv(value)
+	}
+	return "'" + value + "'"
+}
+
+func encodeUnquotedDotenv(value string) string {
+	if value == "" || !strings.ContainsAny(value, " \t\n#'\"\\$") {
+		return value
+	}
+	return encodeDoubleQuotedDotenv(value)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonFrame is an object or array being read from a JSON document.
+type jsonFrame struct {
+	isObject  bool
+	expectKey bool
+	key       string
+	index     int
+}
+
+// parseJSONDocument finds the string values of a JSON document, which are replaced in place so
+// that the document's formatting is kept.
+func parseJSONDocument(source string) (*document, error) {
+	spliced := &splicedDocument{source: source}
+	doc := &document{render: spliced.render}
+
+	decoder := json.NewDecoder(strings.NewReader(source))
+	decoder.UseNumber()
+	var frames []*jsonFrame
+	// valueRead advances the enclosing object or array past the value which was just read.
+	valueRead := func() {
+		if len(frames) == 0 {
+			return
+		}
+		if frame := frames[len(frames)-1]; frame.isObject {
+			frame.expectKey = true
+		} else {
+			frame.index++
+		}
+	}
+
+	for {
+		offset := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			if len(frames) > 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case json.Delim:
+			switch token {
+			case '{', '[':
+				frames = append(frames, &jsonFrame{isObject: token == '{', expectKey: token == '{'})
+			case '}', ']':
+				frames = frames[:len(frames)-1]
+				valueRead()
+			}
+		case string:
+			if len(frames) > 0 && frames[len(frames)-1].expectKey {
+				frames[len(frames)-1].key, frames[len(frames)-1].expectKey = token, false
+				continue
+			}
+			start := offset + strings.IndexByte(source[offset:], '"')
+			spliced.add(doc, jsonLocation(frames), token, start, int(decoder.InputOffset()), encodeJSONString)
+			valueRead()
+		default:
+			valueRead()
+		}
+	}
+
+	return doc, nil
+}
+
+// jsonLocation returns the JSONPath of the value being read.
+func jsonLocation(frames []*jsonFrame) string {
+	var location strings.Builder
+	location.WriteString("$")
+	for _, frame := range frames {
+		if frame.isObject {
+			location.WriteString("." + frame.key)
+		} else {
+			fmt.Fprintf(&location, "[%d]", frame.index)
+		}
+	}
+	return location.String()
+}
+
+func encodeJSONString(value string) string {
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+	return strings.TrimSuffix(encoded.String(), "\n")
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"strconv"
+	"strings"
+)
+
+const propertiesWhitespace = " \t\f"
+
+// parsePropertiesDocument finds the values of a Java .properties document, each of which is
+// replaced in place so that comments and the rest of the document are kept. A value continued
+// over several lines is searched for references line by line, so that each line is replaced
+// in place and a reference must not span a continuation.
+func parsePropertiesDocument(source string) (*document, error) {
+	spliced := &splicedDocument{source: source}
+	doc := &document{render: spliced.render}
+
+	for pos := 0; pos < len(source); {
+		lineEnd := lineEndIndex(source, pos)
+		content := strings.TrimLeft(source[pos:lineEnd], propertiesWhitespace)
+		if content == "" || content[0] == '#' || content[0] == '!' {
+			pos = nextLineIndex(source, lineEnd)
+			continue
+		}
+
+		var key string
+		for segmentStart, first := lineEnd-len(content), true; ; first = false {
+			segmentEnd := lineEnd
+			continued := trailingBackslashes(source[segmentStart:lineEnd])%2 == 1
+			if continued {
+				segmentEnd--
+			}
+			valueStart := segmentStart
+			if first {
+				var offset int
+				key, offset = splitPropertiesLine(source[segmentStart:segmentEnd])
+				key, valueStart = unescapeProperties(key), segmentStart+offset
+			}
+			spliced.add(doc, key, unescapeProperties(source[valueStart:segmentEnd]), valueStart, segmentEnd,
+				escapeProperties)
+
+			if !continued || lineEnd >= len(source) {
+				break
+			}
+			// The leading whitespace of a continuation line is not part of the value.
+			pos = nextLineIndex(source, lineEnd)
+			lineEnd = lineEndIndex(source, pos)
+			segmentStart = lineEnd - len(strings.TrimLeft(source[pos:lineEnd], propertiesWhitespace))
+		}
+		pos = nextLineIndex(source, lineEnd)
+	}
+
+	return doc, nil
+}
+
+// lineEndIndex returns the index of the end of the line starting at pos, excluding its line break.
+func lineEndIndex(source string, pos int) int {
+	end := len(source)
+	if i := strings.IndexByte(source[pos:], '\n'); i >= 0 {
+		end = pos + i
+	}
+	if end > pos && source[end-1] == '\r' {
+		end--
+	}
+	return end
+}
+
+// nextLineIndex returns the index of the start of the line following the line ending at end.
+func nextLineIndex(source string, end int) int {
+	if i := strings.IndexByte(source[end:], '\n'); i >= 0 {
+		return end + i + 1
+	}
+	return len(source)
+}
+
+func trailingBackslashes(line string) int {
+	count := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		count++
+	}
+	return count
+}
+
+// splitPropertiesLine returns the key of a line and the index at which its value
+// starts, the key being terminated by the first unescaped "=", ":" or whitespace.
+func splitPropertiesLine(line string) (string, int) {
+	keyEnd := 0
+	for keyEnd < len(line) && !strings.ContainsRune("=:"+propertiesWhitespace, rune(line[keyEnd])) {
+		if line[keyEnd] == '\\' {
+			keyEnd++
+		}
+		keyEnd++
+	}
+	keyEnd = min(keyEnd, len(line))
+
+	valueStart := keyEnd
+	for valueStart < len(line) && strings.ContainsRune(propertiesWhitespace, rune(line[valueStart])) {
+		valueStart++
+	}
+	if valueStart < len(line) && (line[valueStart] == '=' || line[valueStart] == ':') {
+		valueStart++
+		for valueStart < len(line) && strings.ContainsRune(propertiesWhitespace, rune(line[valueStart])) {
+			valueStart++
+		}
+	}
+	return line[:keyEnd], valueStart
+}
+
+func unescapeProperties(value string) string {
+	var unescaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			unescaped.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 't':
+			unescaped.WriteByte('\t')
+		case 'n':
+			unescaped.WriteByte('\n')
+		case 'r':
+			unescaped.WriteByte('\r')
+		case 'f':
+			unescaped.WriteByte('\f')
+		case 'u':
+			if i+4 < len(value) {
+				if code, err := strconv.ParseUint(value[i+1:i+5], 16, 16); err == nil {
+					unescaped.WriteRune(rune(code))
+					i += 4
+					continue
+				}
+			}
+			unescaped.WriteByte('u')
+		default:
+			unescaped.WriteByte(value[i])
+		}
+	}
+	return unescaped.String()
+}
+
+func escapeProperties(value string) string {
+	var escaped strings.Builder
+	for i, char := range value {
+		switch char {
+		case '\\':
+			escaped.WriteString(`\\`)
+		case '\t':
+			escaped.WriteString(`\t`)
+		case '\n':
+			escaped.WriteString(`\n`)
+		case '\r':
+			escaped.WriteString(`\r`)
+		case '\f':
+			escaped.WriteString(`\f`)
+		case ' ', '=', ':', '#', '!':
+			// A leading space, "=" or ":" would otherwise be taken as part of the separator, and a
+			// leading "#" or "!" as the start of a comment.
+			if i == 0 {
+				escaped.WriteRune('\\')
+			}
+			escaped.WriteRune(char)
+		default:
+			escaped.WriteRune(char)
+		}
+	}
+	return escaped.String()
+}
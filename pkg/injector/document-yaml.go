@@ -0,0 +1,200 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlValue is a string scalar of a YAML document along with its location in the document.
+type yamlValue struct {
+	node     *yaml.Node
+	location string
+	// flow is whether the scalar is within a flow collection, where more characters need quoting.
+	flow bool
+}
+
+// parseYAMLDocument finds the string scalars of a YAML document, which may contain several
+// documents. Scalars written on a single line are replaced in place so that the document's
+// formatting and comments are kept; should any scalar containing a reference span several lines,
+// the whole document is re-encoded instead. Plain scalars are replaced as written, so that the
+// resolved value's type is that of the value, whereas quoted and empty scalars remain strings.
+func parseYAMLDocument(source string) (*document, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(source))
+	var roots []*yaml.Node
+	for {
+		root := &yaml.Node{}
+		err := decoder.Decode(root)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+	}
+
+	var values []yamlValue
+	for _, root := range roots {
+		walkYAML(root, "$", false, func(value yamlValue) {
+			if hasReference(value.node.Value) {
+				values = append(values, value)
+			}
+		})
+	}
+
+	spliced := &splicedDocument{source: source}
+	doc := &document{render: spliced.render}
+	lineStarts := []int{0}
+	for i := range source {
+		if source[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	for _, value := range values {
+		start, end, ok := yamlScalarExtent(source, lineStarts, value.node)
+		if !ok {
+			return reencodedYAMLDocument(roots, values), nil
+		}
+		spliced.add(doc, value.location, value.node.Value, start, end, encodeYAMLScalar(value.node.Style, value.flow))
+	}
+
+	return doc, nil
+}
+
+// walkYAML visits the string scalars within the node which are not mapping keys.
+func walkYAML(node *yaml.Node, location string, flow bool, visit func(yamlValue)) {
+	flow = flow || node.Style&yaml.FlowStyle != 0
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			walkYAML(child, location, flow, visit)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			walkYAML(node.Content[i+1], location+"."+node.Content[i].Value, flow, visit)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			walkYAML(child, fmt.Sprintf("%s[%d]", location, i), flow, visit)
+		}
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!str" {
+			visit(yamlValue{node: node, location: location, flow: flow})
+		}
+	}
+}
+
+// yamlScalarExtent returns the range of the source in which the scalar is written, when it is
+// a plain or quoted scalar on a single line.
+func yamlScalarExtent(source string, lineStarts []int, node *yaml.Node) (int, int, bool) {
+	if node.Line < 1 || node.Line > len(lineStarts) {
+		return 0, 0, false
+	}
+	// Columns count characters rather than bytes.
+	start := lineStarts[node.Line-1]
+	for column := 1; column < node.Column && start < len(source); column++ {
+		_, size := utf8.DecodeRuneInString(source[start:])
+		start += size
+	}
+
+	switch node.Style {
+	case 0:
+		end := start + len(node.Value)
+		return start, end, end <= len(source) && source[start:end] == node.Value
+	case yaml.SingleQuotedStyle:
+		quoted := "'" + strings.ReplaceAll(node.Value, "'", "''") + "'"
+		end := start + len(quoted)
+		return start, end, end <= len(source) && source[start:end] == quoted
+	case yaml.DoubleQuotedStyle:
+		if start >= len(source) || source[start] != '"' {
+			return 0, 0, false
+		}
+		for i := start + 1; i < len(source); i++ {
+			switch source[i] {
+			case '\\':
+				i++
+			case '\n':
+				return 0, 0, false
+			case '"':
+				return start, i + 1, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// encodeYAMLScalar returns the function encoding a replacement value for a single line scalar
+// of the given style, falling back to double quotes when the style cannot represent it.
+func encodeYAMLScalar(style yaml.Style, flow bool) func(string) string {
+	return func(value string) string {
+		node := &yaml.Node{Kind: yaml.ScalarNode, Style: style, Value: value}
+		if style == 0 && (value == "" || flow && strings.ContainsAny(value, ",[]{}")) {
+			node.Style = yaml.DoubleQuotedStyle
+		}
+		encoded := encodeYAMLNode(node)
+		if strings.Contains(encoded, "\n") {
+			node.Style = yaml.DoubleQuotedStyle
+			encoded = encodeYAMLNode(node)
+		}
+		return encoded
+	}
+}
+
+func encodeYAMLNode(node *yaml.Node) string {
+	encoded, err := yaml.Marshal(node)
+	if err != nil {
+		// Scalars which cannot be encoded as they are written are always valid double quoted.
+		node.Style = yaml.DoubleQuotedStyle
+		encoded, _ = yaml.Marshal(node)
+	}
+	return strings.TrimSuffix(string(encoded), "\n")
+}
+
+// reencodedYAMLDocument is a YAML document which is encoded from its parsed nodes once the
+// values have been applied to them.
+func reencodedYAMLDocument(roots []*yaml.Node, values []yamlValue) *document {
+	doc := &document{render: func() string {
+		var rendered strings.Builder
+		encoder := yaml.NewEncoder(&rendered)
+		encoder.SetIndent(2)
+		for _, root := range roots {
+			_ = encoder.Encode(root)
+		}
+		_ = encoder.Close()
+		return rendered.String()
+	}}
+	for _, value := range values {
+		node := value.node
+		doc.values = append(doc.values, documentValue{location: value.location, value: node.Value, apply: func(value string) {
+			node.Value = value
+			switch {
+			case node.Style == 0 && value == "":
+				node.Style = yaml.DoubleQuotedStyle
+			case node.Style == 0:
+				node.Tag = ""
+			}
+		}})
+	}
+	return doc
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// documentKeysAnnotation lists the comma-separated ConfigMap keys whose values are structured
+// documents, in which references are resolved within each string rather than the value as a
+// whole. A key's format is given as "<key>=<format>" or is otherwise taken from its extension.
+const documentKeysAnnotation = annotationPrefix + "document-keys"
+
+const (
+	yamlFormat       = "yaml"
+	jsonFormat       = "json"
+	propertiesFormat = "properties"
+	dotenvFormat     = "env"
+)
+
+// documentParsers parse a document of each supported format.
+var documentParsers = map[string]func(source string) (*document, error){
+	yamlFormat:       parseYAMLDocument,
+	jsonFormat:       parseJSONDocument,
+	propertiesFormat: parsePropertiesDocument,
+	dotenvFormat:     parseDotenvDocument,
+}
+
+// documentFormatExtensions maps file extensions to the format of documents with them.
+var documentFormatExtensions = map[string]string{
+	".yaml":       yamlFormat,
+	".yml":        yamlFormat,
+	".json":       jsonFormat,
+	".properties": propertiesFormat,
+	".env":        dotenvFormat,
+}
+
+// document is a parsed structured document along with the strings within it.
+type document struct {
+	values []documentValue
+	// render writes the document with any values which have been applied.
+	render func() string
+}
+
+// documentValue is a string within a document, identified by its location in the document.
+type documentValue struct {
+	location string
+	value    string
+	apply    func(value string)
+}
+
+// splicedDocument renders a document by replacing the source text of each applied value,
+// leaving the rest of the document exactly as it was written.
+type splicedDocument struct {
+	source  string
+	splices []*splice
+}
+
+// splice is the source text of a value, between start and end, along with the function used
+// to encode its replacement in the document's syntax.
+type splice struct {
+	start, end int
+	encode     func(value string) string
+	replaced   *string
+}
+
+// add registers the value found between start and end of the source, which must follow
+// those already added.
+func (d *splicedDocument) add(
+	doc *document,
+	location string,
+	value string,
+	start int,
+	end int,
+	encode func(string) string,
+) {
+	s := &splice{start: start, end: end, encode: encode}
+	d.splices = append(d.splices, s)
+	doc.values = append(doc.values, documentValue{location: location, value: value, apply: func(value string) {
+		s.replaced = &value
+	}})
+}
+
+func (d *splicedDocument) render() string {
+	var rendered strings.Builder
+	last := 0
+	for _, s := range d.splices {
+		if s.replaced == nil {
+			continue
+		}
+		rendered.WriteString(d.source[last:s.start])
+		rendered.WriteString(s.encode(*s.replaced))
+		last = s.end
+	}
+	rendered.WriteString(d.source[last:])
+	return rendered.String()
+}
+
+// documentFormats returns the format of each ConfigMap key listed in the documentKeysAnnotation.
+func documentFormats(annotations map[string]string) (map[string]string, error) {
+	formats := map[string]string{}
+	for _, item := range splitList(annotations[documentKeysAnnotation]) {
+		key, format, found := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		if !found {
+			format = documentFormatExtensions[path.Ext(key)]
+			if format == "" {
+				return nil, fmt.Errorf("unable to determine the document format of ConfigMap key %s, "+
+					"specify it as %s=<format>", key, key)
+			}
+		}
+		format = strings.TrimSpace(format)
+		if documentParsers[format] == nil {
+			return nil, fmt.Errorf("unknown document format %q for ConfigMap key %s, expected %s, %s, %s or %s",
+				format, key, yamlFormat, jsonFormat, propertiesFormat, dotenvFormat)
+		}
+		formats[key] = format
+	}
+	return formats, nil
+}
+
+// collectDocument registers the strings within the document which reference SSM Parameters,
+// writing the re-rendered document with write as each is applied.
+func collectDocument(refs *parameterReferences, field string, source string, format string, write func(string)) {
+	doc, err := documentParsers[format](source)
+	if err != nil {
+		log.Log.Error(err, "unable to parse "+field+" as "+format)
+		refs.errs = append(refs.errs, fmt.Errorf("invalid %s: unable to parse as %s: %s", field, format, err))
+		return
+	}
+
+	for _, value := range doc.values {
+		refs.add(field+" "+value.location, value.value, func(paramValue string) {
+			value.apply(paramValue)
+			write(doc.render())
+		})
+	}
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"testing"
+)
+
+// resolveDocument parses the document, replaces each of its values found in resolved and
+// renders it.
+func resolveDocument(t *testing.T, format string, source string, resolved map[string]string) (string, error) {
+	t.Helper()
+
+	doc, err := documentParsers[format](source)
+	if err != nil {
+		return "", err
+	}
+	for _, value := range doc.values {
+		if paramValue, ok := resolved[value.value]; ok {
+			value.apply(paramValue)
+		}
+	}
+	return doc.render(), nil
+}
+
+type documentTest struct {
+	name     string
+	source   string
+	resolved map[string]string
+	expected string
+	err      bool
+	// readBack parses the rendered document again, in which every value of the source document
+	// must be found, to check each resolved value is read back as it was resolved.
+	readBack bool
+}
+
+func runDocumentTests(t *testing.T, format string, tests []documentTest) {
+	t.Helper()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rendered, err := resolveDocument(t, format, test.source, test.resolved)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", rendered)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if rendered != test.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", test.expected, rendered)
+			}
+
+			if !test.readBack {
+				return
+			}
+			// Each value is read back from the rendered document as it was resolved.
+			source, err := documentParsers[format](test.source)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			reparsed, err := documentParsers[format](rendered)
+			if err != nil {
+				t.Fatalf("unable to parse the rendered document: %s", err)
+			}
+			if len(reparsed.values) != len(source.values) {
+				t.Fatalf("expected %d values in the rendered document, got %d", len(source.values), len(reparsed.values))
+			}
+			for i, value := range source.values {
+				if paramValue, ok := test.resolved[value.value]; ok && reparsed.values[i].value != paramValue {
+					t.Errorf("expected %s to read back as %q, got %q", value.location, paramValue, reparsed.values[i].value)
+				}
+			}
+		})
+	}
+}
+
+func TestYAMLDocument(t *testing.T) {
+	runDocumentTests(t, yamlFormat, []documentTest{
+		{
+			name:     "plain scalar",
+			source:   "# database\ndb:\n  host: ssm://app/db/host # primary\n  port: 5432\n",
+			resolved: map[string]string{"ssm://app/db/host": "db.local"},
+			expected: "# database\ndb:\n  host: db.local # primary\n  port: 5432\n",
+		},
+		{
+			name:     "plain scalar needing quotes",
+			source:   "password: ssm://app/db/password\n",
+			resolved: map[string]string{"ssm://app/db/password": "a: b #c"},
+			expected: "password: 'a: b #c'\n",
+		},
+		{
+			name:     "empty plain scalar",
+			source:   "password: ssm://app/db/password\n",
+			resolved: map[string]string{"ssm://app/db/password": ""},
+			expected: "password: \"\"\n",
+		},
+		{
+			name:     "double quoted scalar",
+			source:   "url: \"postgres://${ssm://app/db/user}@db\"\n",
+			resolved: map[string]string{"postgres://${ssm://app/db/user}@db": "postgres://\"admin\"\n@db"},
+			expected: "url: \"postgres://\\\"admin\\\"\\n@db\"\n",
+		},
+		{
+			name:     "single quoted scalar",
+			source:   "name: 'ssm://app/name'\n",
+			resolved: map[string]string{"ssm://app/name": "it's"},
+			expected: "name: 'it''s'\n",
+		},
+		{
+			name:     "flow sequence",
+			source:   "hosts: [ssm://app/host, other]\n",
+			resolved: map[string]string{"ssm://app/host": "a,b"},
+			expected: "hosts: [\"a,b\", other]\n",
+		},
+		{
+			name:     "CRLF line endings",
+			source:   "host: ssm://app/db/host\r\nport: 5432\r\n",
+			resolved: map[string]string{"ssm://app/db/host": "db.local"},
+			expected: "host: db.local\r\nport: 5432\r\n",
+		},
+		{
+			name:     "several documents",
+			source:   "a: ssm://app/a\n---\nb: ssm://app/b\n",
+			resolved: map[string]string{"ssm://app/a": "1", "ssm://app/b": "2"},
+			expected: "a: 1\n---\nb: 2\n",
+		},
+		{
+			name:   "invalid document",
+			source: "a: [unterminated\n",
+			err:    true,
+		},
+	})
+}
+
+func TestJSONDocument(t *testing.T) {
+	runDocumentTests(t, jsonFormat, []documentTest{
+		{
+			name:     "nested values",
+			source:   "{\n  \"db\": {\"host\": \"ssm://app/db/host\", \"port\": 5432},\n  \"hosts\": [\"ssm://app/host\"]\n}\n",
+			resolved: map[string]string{"ssm://app/db/host": "db.local", "ssm://app/host": "web.local"},
+			expected: "{\n  \"db\": {\"host\": \"db.local\", \"port\": 5432},\n  \"hosts\": [\"web.local\"]\n}\n",
+		},
+		{
+			name:     "escaped value",
+			source:   `{"password": "ssm://app/db/password"}`,
+			resolved: map[string]string{"ssm://app/db/password": "a\"b\\c\n<d>"},
+			expected: `{"password": "a\"b\\c\n<d>"}`,
+			readBack: true,
+		},
+		{
+			name:     "escaped reference",
+			source:   `{"url": "\u0024{ssm://app/url}\/x"}`,
+			resolved: map[string]string{"${ssm://app/url}/x": "https://example.com/x"},
+			expected: `{"url": "https://example.com/x"}`,
+		},
+		{
+			name:     "CRLF line endings",
+			source:   "{\r\n  \"host\": \"ssm://app/db/host\"\r\n}\r\n",
+			resolved: map[string]string{"ssm://app/db/host": "db.local"},
+			expected: "{\r\n  \"host\": \"db.local\"\r\n}\r\n",
+		},
+		{
+			name:   "invalid document",
+			source: `{"host": "ssm://app/db/host"`,
+			err:    true,
+		},
+	})
+}
+
+func TestPropertiesDocument(t *testing.T) {
+	runDocumentTests(t, propertiesFormat, []documentTest{
+		{
+			name:     "separators",
+			source:   "a=ssm://app/a\nb : ssm://app/b\nc ssm://app/c\n",
+			resolved: map[string]string{"ssm://app/a": "1", "ssm://app/b": "2", "ssm://app/c": "3"},
+			expected: "a=1\nb : 2\nc 3\n",
+		},
+		{
+			name:     "comments kept",
+			source:   "# ssm://app/a\n! ssm://app/a\na=ssm://app/a\n",
+			resolved: map[string]string{"ssm://app/a": "1"},
+			expected: "# ssm://app/a\n! ssm://app/a\na=1\n",
+		},
+		{
+			name:     "escaped value",
+			source:   "password=ssm://app/db/password\n",
+			resolved: map[string]string{"ssm://app/db/password": " a\\b\nc"},
+			expected: "password=\\ a\\\\b\\nc\n",
+		},
+		{
+			name:     "leading separator and comment characters escaped",
+			source:   "a ssm://app/a\nb=ssm://app/b\nc:ssm://app/c\nd=${ssm://app/d}\n",
+			resolved: map[string]string{"ssm://app/a": "=v", "ssm://app/b": ":v", "ssm://app/c": "#v", "${ssm://app/d}": "!v=w"},
+			expected: "a \\=v\nb=\\:v\nc:\\#v\nd=\\!v=w\n",
+			readBack: true,
+		},
+		{
+			name:     "escaped key",
+			source:   "a\\ key=ssm://app/a\n",
+			resolved: map[string]string{"ssm://app/a": "1"},
+			expected: "a\\ key=1\n",
+		},
+		{
+			name:     "CRLF line endings",
+			source:   "a=ssm://app/a\r\nb=2\r\n",
+			resolved: map[string]string{"ssm://app/a": "1"},
+			expected: "a=1\r\nb=2\r\n",
+		},
+		{
+			name:     "reference on a continuation line",
+			source:   "key=multi\\\n   ssm://app/z\nnext=1\n",
+			resolved: map[string]string{"ssm://app/z": "resolved"},
+			expected: "key=multi\\\n   resolved\nnext=1\n",
+		},
+		{
+			name:     "reference before a continuation",
+			source:   "url=${ssm://app/host}\\\r\n  /path\r\n",
+			resolved: map[string]string{"${ssm://app/host}": "https://example.com"},
+			expected: "url=https://example.com\\\r\n  /path\r\n",
+		},
+		{
+			name:     "escaped backslash is not a continuation",
+			source:   "a=ssm://app/a\\\\\nb=ssm://app/b\n",
+			resolved: map[string]string{"ssm://app/a\\": "1", "ssm://app/b": "2"},
+			expected: "a=1\nb=2\n",
+		},
+	})
+}
+
+func TestDotenvDocument(t *testing.T) {
+	runDocumentTests(t, dotenvFormat, []documentTest{
+		{
+			name:     "unquoted values",
+			source:   "# database\nDB_HOST=ssm://app/db/host # primary\nexport DB_USER=ssm://app/db/user\nPORT=5432\n",
+			resolved: map[string]string{"ssm://app/db/host": "db.local", "ssm://app/db/user": "admin"},
+			expected: "# database\nDB_HOST=db.local # primary\nexport DB_USER=admin\nPORT=5432\n",
+		},
+		{
+			name:     "unquoted value needing quotes",
+			source:   "PASSWORD=ssm://app/db/password\n",
+			resolved: map[string]string{"ssm://app/db/password": "a b#\"c"},
+			expected: "PASSWORD=\"a b#\\\"c\"\n",
+			readBack: true,
+		},
+		{
+			name:     "double quoted value",
+			source:   "URL=\"postgres://${ssm://app/db/user}@db\"\n",
+			resolved: map[string]string{"postgres://${ssm://app/db/user}@db": "postgres://\"admin\"\n@db"},
+			expected: "URL=\"postgres://\\\"admin\\\"\\n@db\"\n",
+			readBack: true,
+		},
+		{
+			name:     "dollar signs escaped",
+			source:   "A=ssm://app/a\nB=\"ssm://app/b\"\nC='ssm://app/c'\n",
+			resolved: map[string]string{"ssm://app/a": "p$HOME", "ssm://app/b": "${USER}", "ssm://app/c": "$x"},
+			expected: "A=\"p\\$HOME\"\nB=\"\\${USER}\"\nC='$x'\n",
+			readBack: true,
+		},
+		{
+			name:     "single quoted value",
+			source:   "export NAME='ssm://app/name'\nOTHER='ssm://app/other'\n",
+			resolved: map[string]string{"ssm://app/name": "a \"b\"", "ssm://app/other": "it's"},
+			expected: "export NAME='a \"b\"'\nOTHER=\"it's\"\n",
+		},
+		{
+			name:     "CRLF line endings",
+			source:   "A=ssm://app/a\r\nB=\"ssm://app/b\"\r\n",
+			resolved: map[string]string{"ssm://app/a": "1", "ssm://app/b": "2"},
+			expected: "A=1\r\nB=\"2\"\r\n",
+		},
+		{
+			name:   "unterminated double quoted value",
+			source: "A=\"ssm://app/a\nB=2\n",
+			err:    true,
+		},
+		{
+			name:   "unterminated single quoted value",
+			source: "A='ssm://app/a\n",
+			err:    true,
+		},
+	})
+}
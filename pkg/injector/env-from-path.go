@@ -0,0 +1,194 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"aedificans.com/k8s-ssm-param-injector/pkg/provider"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// envFromPathAnnotation lists the comma-separated parameter paths whose parameters are
+	// injected as env vars into the containers of a pod or pod template.
+	envFromPathAnnotation = annotationPrefix + "env-from-path"
+	// envFromPathContainersAnnotation limits injection to the comma-separated container names,
+	// which may include init containers. Every container other than init containers is
+	// selected by default.
+	envFromPathContainersAnnotation = annotationPrefix + "env-from-path-containers"
+	// envFromPathPrefixAnnotation is prepended to the name of every generated env var.
+	envFromPathPrefixAnnotation = annotationPrefix + "env-from-path-prefix"
+	// envFromPathCaseAnnotation converts generated env var names to "upper" case, the default,
+	// or "lower" case, or leaves them as they are with "preserve".
+	envFromPathCaseAnnotation = annotationPrefix + "env-from-path-case"
+	// envFromPathGeneratedAnnotation records the names of the env vars generated in each
+	// container as a JSON object, so that they are replaced when the object is admitted again
+	// rather than kept as explicit env vars.
+	envFromPathGeneratedAnnotation = annotationPrefix + "env-from-path-generated"
+)
+
+const (
+	upperCase    = "upper"
+	lowerCase    = "lower"
+	preserveCase = "preserve"
+)
+
+var invalidEnvVarNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// collectEnvFromPath registers the parameter paths of the envFromPathAnnotation, if any, to be
+// injected as env vars into the selected containers of the pod spec.
+func collectEnvFromPath(refs *parameterReferences, objectMeta *metav1.ObjectMeta, podSpec *corev1.PodSpec) {
+	paths := splitList(objectMeta.Annotations[envFromPathAnnotation])
+	if len(paths) == 0 {
+		return
+	}
+
+	nameCase := objectMeta.Annotations[envFromPathCaseAnnotation]
+	switch nameCase {
+	case "":
+		nameCase = upperCase
+	case upperCase, lowerCase, preserveCase:
+	default:
+		refs.errs = append(refs.errs, fmt.Errorf("invalid %s annotation %q, expected %s, %s or %s",
+			envFromPathCaseAnnotation, nameCase, upperCase, lowerCase, preserveCase))
+		return
+	}
+	prefix := objectMeta.Annotations[envFromPathPrefixAnnotation]
+	containers := selectContainers(podSpec, splitList(objectMeta.Annotations[envFromPathContainersAnnotation]))
+
+	refs.addPaths(envFromPathAnnotation+" annotation", paths, func(pathValues map[string]map[string]provider.Parameter) {
+		// Later paths take precedence over earlier ones when they generate the same name.
+		envValues := map[string]provider.Parameter{}
+		for _, path := range paths {
+			for paramName, value := range pathValues[path] {
+				name := envVarName(path, paramName, prefix, nameCase)
+				if errs := validation.IsEnvVarName(name); len(errs) > 0 {
+					refs.warnings = append(refs.warnings, fmt.Sprintf("skipping SSM Parameter %s: invalid env var name %q: %s",
+						paramName, name, strings.Join(errs, ", ")))
+					continue
+				}
+				envValues[name] = value
+			}
+		}
+		names := make([]string, 0, len(envValues))
+		for name := range envValues {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		previous := map[string][]string{}
+		if previousJson := objectMeta.Annotations[envFromPathGeneratedAnnotation]; previousJson != "" {
+			if err := json.Unmarshal([]byte(previousJson), &previous); err != nil {
+				log.Log.Error(err, "ignoring invalid "+envFromPathGeneratedAnnotation+" annotation")
+			}
+		}
+		generated := map[string][]string{}
+		for _, container := range containers {
+			env := make([]corev1.EnvVar, 0, len(container.Env)+len(names))
+			explicit := map[string]bool{}
+			for _, envVar := range container.Env {
+				if !slices.Contains(previous[container.Name], envVar.Name) {
+					env = append(env, envVar)
+					explicit[envVar.Name] = true
+				}
+			}
+			for _, name := range names {
+				if explicit[name] {
+					log.Log.WithValues("container", container.Name, "name", name).
+						Info("Env var already set explicitly, skipping the one generated from " + envFromPathAnnotation)
+					continue
+				}
+				envVar := corev1.EnvVar{Name: name, Value: envValues[name].Value}
+				if refs.secret != nil && envValues[name].IsSecure() {
+					envVar.Value = ""
+					envVar.ValueFrom = &corev1.EnvVarSource{
+						SecretKeyRef: refs.secret.add(container.Name+"."+name, envValues[name].Value),
+					}
+				}
+				env = append(env, envVar)
+				generated[container.Name] = append(generated[container.Name], name)
+			}
+			container.Env = env
+		}
+
+		if len(generated) > 0 {
+			generatedJson, _ := json.Marshal(generated)
+			objectMeta.Annotations[envFromPathGeneratedAnnotation] = string(generatedJson)
+		} else {
+			delete(objectMeta.Annotations, envFromPathGeneratedAnnotation)
+		}
+	})
+}
+
+// selectContainers returns the named containers and init containers of the pod spec, or every
+// container other than init containers when no names are given.
+func selectContainers(podSpec *corev1.PodSpec, names []string) []*corev1.Container {
+	var containers []*corev1.Container
+	for i := range podSpec.Containers {
+		if len(names) == 0 || slices.Contains(names, podSpec.Containers[i].Name) {
+			containers = append(containers, &podSpec.Containers[i])
+		}
+	}
+	for i := range podSpec.InitContainers {
+		if len(names) > 0 && slices.Contains(names, podSpec.InitContainers[i].Name) {
+			containers = append(containers, &podSpec.InitContainers[i])
+		}
+	}
+	return containers
+}
+
+// relativeParameterName returns the name of the parameter relative to the path it is beneath,
+// whether or not either has a leading "/".
+func relativeParameterName(path string, paramName string) string {
+	path = strings.TrimPrefix(strings.TrimSuffix(path, "/")+"/", "/")
+	return strings.TrimPrefix(strings.TrimPrefix(paramName, "/"), path)
+}
+
+// envVarName derives an env var name from the parameter's name relative to the path, so that
+// "/app/prod/db/host" beneath "/app/prod/" becomes "DB_HOST" in upper case. Characters which
+// are not valid in shell variable names, including the separators of nested paths, are
+// replaced with underscores.
+func envVarName(path string, paramName string, prefix string, nameCase string) string {
+	name := invalidEnvVarNameChars.ReplaceAllString(relativeParameterName(path, paramName), "_")
+	switch nameCase {
+	case upperCase:
+		name = strings.ToUpper(name)
+	case lowerCase:
+		name = strings.ToLower(name)
+	}
+	return prefix + name
+}
+
+// splitList splits a comma-separated annotation value, ignoring whitespace and empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
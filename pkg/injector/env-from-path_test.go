@@ -0,0 +1,86 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"maps"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newEnvFromPathPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app",
+			Namespace:   "default",
+			Annotations: map[string]string{envFromPathAnnotation: "/app/env"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "app",
+				Env:  []corev1.EnvVar{{Name: "LEVEL", Value: "info"}},
+			}},
+		},
+	}
+}
+
+func TestEnvFromPathExplicitEnvVar(t *testing.T) {
+	s := newTestInjector(fakeProvider{"/app/env/LEVEL": {Value: "debug"}, "/app/env/REGION": {Value: "eu-west-1"}})
+
+	patched := &corev1.Pod{}
+	resp := admit(t, s, admissionv1.Create, newEnvFromPathPod(), patched)
+	if !resp.Allowed {
+		t.Fatalf("expected the Pod to be allowed, got %v", resp.Result)
+	}
+
+	// The explicit env var takes precedence over the one generated from the path.
+	expected := map[string]string{"LEVEL": "info", "REGION": "eu-west-1"}
+	env := map[string]string{}
+	for _, envVar := range patched.Spec.Containers[0].Env {
+		env[envVar.Name] = envVar.Value
+	}
+	if !maps.Equal(env, expected) {
+		t.Errorf("expected env %v, got %v", expected, env)
+	}
+}
+
+func TestEnvFromPathPodUpdate(t *testing.T) {
+	s := newTestInjector(fakeProvider{"/app/env/REGION": {Value: "eu-west-1"}})
+
+	resp := admit(t, s, admissionv1.Update, newEnvFromPathPod(), &corev1.Pod{})
+	if !resp.Allowed || len(resp.Patches) > 0 {
+		t.Errorf("expected the Pod update to be allowed unmodified, got %v with %v", resp.Result, resp.Patches)
+	}
+}
+
+func TestEnvFromPathInheritedFromTemplate(t *testing.T) {
+	s := newTestInjector(fakeProvider{})
+	s.Provider = failingProvider{Provider: s.Provider, name: "/app/env"}
+
+	// A Pod created from an injected pod template has its generated env vars and annotation.
+	pod := newEnvFromPathPod()
+	pod.Annotations[envFromPathGeneratedAnnotation] = `{"app":["REGION"]}`
+	pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env, corev1.EnvVar{Name: "REGION", Value: "eu-west-1"})
+
+	resp := admit(t, s, admissionv1.Create, pod, &corev1.Pod{})
+	if !resp.Allowed || len(resp.Patches) > 0 {
+		t.Errorf("expected the Pod to be allowed unmodified, got %v with %v", resp.Result, resp.Patches)
+	}
+}
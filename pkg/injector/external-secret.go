@@ -18,57 +18,34 @@ package injector
 
 import (
 	"context"
-	"encoding/json"
-	"net/http"
-	"strings"
+	"slices"
 
-	externalsecrets "github.com/external-secrets/external-secrets/apis/externalsecrets/v1beta1"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
-func (s *SSMParameterInjector) handleExternalSecret(ctx context.Context, req admission.Request) admission.Response {
-	externalSecret := &externalsecrets.ExternalSecret{}
-
-	log.Log.V(1).Info("Decoding ExternalSecret from request")
-	err := s.Decoder.Decode(req, externalSecret)
-	if err != nil {
-		log.Log.Error(err, "unable to decode ExternalSecret")
-		return admission.Errored(http.StatusBadRequest, err)
-	}
-	log.Log.WithValues("name", externalSecret.Name, "namespace", externalSecret.Namespace).
-		V(1).Info("ExternalSecret successfully decoded")
-
-	wasModified := false
-	if externalSecret.Spec.Data != nil {
-		for i, data := range externalSecret.Spec.Data {
-			if strings.HasPrefix(data.RemoteRef.Key, "ssm:/") {
-				log.Log.Info("SSM Parameter detected in ExternalSecret remoteRef.key")
-				log.Log.WithValues("paramKey", data.RemoteRef.Key).
-					V(1).Info("SSM Parameter detected")
-				paramName := strings.TrimPrefix(data.RemoteRef.Key, "ssm:/")
-				paramValue, err := s.getSSMParameter(ctx, paramName)
-				if err != nil {
-					return admission.Errored(http.StatusInternalServerError, err)
-				}
-				log.Log.Info("Updating ExternalSecret remoteRef.key with SSM Parameter value")
-				externalSecret.Spec.Data[i].RemoteRef.Key = paramValue
-				wasModified = true
-			}
-		}
-	}
+// externalSecretSpecFieldPaths select the fields of an ExternalSecret's spec which may
+// reference SSM Parameters, in both the v1 and v1beta1 APIs. Template data is searched in its
+// entirety.
+var externalSecretSpecFieldPaths = []string{
+	".data[*].remoteRef.key",
+	".data[*].remoteRef.property",
+	".dataFrom[*].extract.key",
+	".dataFrom[*].extract.property",
+	".dataFrom[*].find.path",
+	".target.template.data",
+}
 
-	if !wasModified {
-		log.Log.Info("No SSM parameters found")
-		return admission.Allowed("No modifications required")
-	}
+var (
+	externalSecretFieldPaths        = mustParseFieldPaths(".spec", externalSecretSpecFieldPaths)
+	clusterExternalSecretFieldPaths = mustParseFieldPaths(".spec.externalSecretSpec", externalSecretSpecFieldPaths)
+)
 
-	externalSecretJson, err := json.Marshal(externalSecret)
-	if err != nil {
-		log.Log.Error(err, "unable to marshal modified ExternalSecret to JSON")
-		return admission.Errored(http.StatusInternalServerError, err)
+// handleExternalSecret searches ExternalSecrets and ClusterExternalSecrets for SSM Parameter references.
+func (s *SSMParameterInjector) handleExternalSecret(ctx context.Context, req admission.Request) admission.Response {
+	fieldPaths := slices.Clone(externalSecretFieldPaths)
+	if req.Kind.Kind == "ClusterExternalSecret" {
+		fieldPaths = slices.Clone(clusterExternalSecretFieldPaths)
 	}
 
-	log.Log.Info("Returning JSON patch for value injection(s)")
-	return admission.PatchResponseFromRaw(req.Object.Raw, externalSecretJson)
+	return s.injectUnstructured(ctx, req, append(fieldPaths, s.configuredFieldPaths(req)...))
 }
@@ -0,0 +1,169 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const externalSecretSpec = `
+refreshInterval: 1h
+secretStoreRef:
+  kind: ClusterSecretStore
+  name: vault
+data:
+- secretKey: password
+  remoteRef:
+    key: ssm://app/vault/key
+    property: ssm://app/vault/property
+    decodingStrategy: None
+dataFrom:
+- extract:
+    key: ssm://app/vault/extract
+    property: ${ssm://app/vault/property}
+- find:
+    path: ssm://app/vault/path
+    name:
+      regexp: ssm://app/not/searched
+target:
+  name: app
+  template:
+    engineVersion: v2
+    data:
+      url: postgres://{{ .password }}@${ssm://app/db/host}/app
+`
+
+// expectedExternalSecretSpec is externalSecretSpec with every searched field resolved.
+const expectedExternalSecretSpec = `
+refreshInterval: 1h
+secretStoreRef:
+  kind: ClusterSecretStore
+  name: vault
+data:
+- secretKey: password
+  remoteRef:
+    key: app/password
+    property: password
+    decodingStrategy: None
+dataFrom:
+- extract:
+    key: app/config
+    property: password
+- find:
+    path: app/shared
+    name:
+      regexp: ssm://app/not/searched
+target:
+  name: app
+  template:
+    engineVersion: v2
+    data:
+      url: postgres://{{ .password }}@db.local/app
+`
+
+func newExternalSecretTestInjector() *SSMParameterInjector {
+	return newTestInjector(fakeProvider{
+		"/app/vault/key":      {Value: "app/password"},
+		"/app/vault/property": {Value: "password"},
+		"/app/vault/extract":  {Value: "app/config"},
+		"/app/vault/path":     {Value: "app/shared"},
+		"/app/db/host":        {Value: "db.local"},
+	})
+}
+
+// decodeSpec decodes a YAML spec into unstructured content.
+func decodeSpec(t *testing.T, spec string) map[string]any {
+	t.Helper()
+
+	decoded := map[string]any{}
+	if err := yaml.Unmarshal([]byte(spec), &decoded); err != nil {
+		t.Fatalf("unable to decode spec: %s", err)
+	}
+	return decoded
+}
+
+func TestHandleExternalSecret(t *testing.T) {
+	s := newExternalSecretTestInjector()
+
+	tests := []struct {
+		apiVersion string
+		kind       string
+		// specPath is the path to the ExternalSecret spec within the resource's spec.
+		specPath []string
+	}{
+		{apiVersion: "external-secrets.io/v1", kind: "ExternalSecret"},
+		{apiVersion: "external-secrets.io/v1beta1", kind: "ExternalSecret"},
+		{apiVersion: "external-secrets.io/v1", kind: "ClusterExternalSecret", specPath: []string{"externalSecretSpec"}},
+		{apiVersion: "external-secrets.io/v1beta1", kind: "ClusterExternalSecret", specPath: []string{"externalSecretSpec"}},
+	}
+	for _, test := range tests {
+		t.Run(test.apiVersion+" "+test.kind, func(t *testing.T) {
+			object := &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": test.apiVersion,
+				"kind":       test.kind,
+				"metadata":   map[string]any{"name": "app", "namespace": "default"},
+				// Fields unknown to the webhook are kept as they are.
+				"status": map[string]any{"refreshTime": "2024-01-01T00:00:00Z"},
+			}}
+			if err := unstructured.SetNestedField(object.Object, decodeSpec(t, externalSecretSpec),
+				append([]string{"spec"}, test.specPath...)...); err != nil {
+				t.Fatalf("unable to set spec: %s", err)
+			}
+			if test.specPath != nil {
+				if err := unstructured.SetNestedField(object.Object, "1m", "spec", "refreshTime"); err != nil {
+					t.Fatalf("unable to set refresh time: %s", err)
+				}
+			}
+			expected := object.DeepCopy()
+			if err := unstructured.SetNestedField(expected.Object, decodeSpec(t, expectedExternalSecretSpec),
+				append([]string{"spec"}, test.specPath...)...); err != nil {
+				t.Fatalf("unable to set spec: %s", err)
+			}
+
+			patched := &unstructured.Unstructured{}
+			resp := admit(t, s, admissionv1.Create, object, patched)
+			if !resp.Allowed {
+				t.Fatalf("expected the %s to be allowed, got %v", test.kind, resp.Result)
+			}
+			if !equality.Semantic.DeepEqual(patched.Object, expected.Object) {
+				t.Errorf("expected:\n%v\ngot:\n%v", expected.Object, patched.Object)
+			}
+		})
+	}
+}
+
+func TestHandleExternalSecretUnsupportedVersion(t *testing.T) {
+	s := newExternalSecretTestInjector()
+
+	// Only the v1 and v1beta1 APIs are searched.
+	object := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "external-secrets.io/v1alpha1",
+		"kind":       "ExternalSecret",
+		"metadata":   map[string]any{"name": "app", "namespace": "default"},
+		"spec":       decodeSpec(t, externalSecretSpec),
+	}}
+
+	resp := admit(t, s, admissionv1.Create, object, &unstructured.Unstructured{})
+	if !resp.Allowed || len(resp.Patches) > 0 {
+		t.Errorf("expected the ExternalSecret to be allowed unmodified, got %v with %v", resp.Result, resp.Patches)
+	}
+}
@@ -0,0 +1,314 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// FieldPathRule selects the fields of a kind of resource which are searched for SSM Parameter
+// references when the resource is handled as unstructured content.
+type FieldPathRule struct {
+	Group string `json:"group"`
+	// Version restricts the rule to a single API version of the kind, or any version if empty.
+	Version    string      `json:"version,omitempty"`
+	Kind       string      `json:"kind"`
+	FieldPaths []FieldPath `json:"fieldPaths"`
+}
+
+// fieldPathsConfig is the document read by LoadFieldPathRules, e.g.
+//
+//	rules:
+//	- group: karpenter.k8s.aws
+//	  version: v1
+//	  kind: EC2NodeClass
+//	  fieldPaths:
+//	  - .spec.subnetSelectorTerms[*].id
+//	  - path: .spec.securityGroupIDs
+//	    expand: true
+type fieldPathsConfig struct {
+	Rules []FieldPathRule `json:"rules"`
+}
+
+// LoadFieldPathRules reads the FieldPathRules from the YAML document at path.
+func LoadFieldPathRules(path string) ([]FieldPathRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read field paths config: %s", err)
+	}
+
+	config := fieldPathsConfig{}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse field paths config: %s", err)
+	}
+
+	for i, rule := range config.Rules {
+		if rule.Kind == "" || len(rule.FieldPaths) == 0 {
+			return nil, fmt.Errorf("invalid field paths config: rule %d requires a kind and field paths", i)
+		}
+	}
+
+	return config.Rules, nil
+}
+
+// matches returns whether the rule applies to resources of the group, version and kind.
+func (r *FieldPathRule) matches(gvk schema.GroupVersionKind) bool {
+	return r.Group == gvk.Group && r.Kind == gvk.Kind && (r.Version == "" || r.Version == gvk.Version)
+}
+
+// ParseFieldPathRule parses a rule given as "<kind>[.<group>]=<field path>[,<field path>...]",
+// e.g. "EC2NodeClass.karpenter.k8s.aws=.spec.subnetSelectorTerms[*].id,.spec.role".
+func ParseFieldPathRule(rule string) (FieldPathRule, error) {
+	groupKind, fieldPaths, found := strings.Cut(rule, "=")
+	if !found || groupKind == "" || fieldPaths == "" {
+		return FieldPathRule{}, fmt.Errorf("invalid field path rule %q, expected <kind>[.<group>]=<field paths>", rule)
+	}
+
+	parsed := FieldPathRule{}
+	parsed.Kind, parsed.Group, _ = strings.Cut(groupKind, ".")
+	for _, fieldPath := range strings.Split(fieldPaths, ",") {
+		path, err := ParseFieldPath(fieldPath)
+		if err != nil {
+			return FieldPathRule{}, err
+		}
+		parsed.FieldPaths = append(parsed.FieldPaths, path)
+	}
+
+	return parsed, nil
+}
+
+// fieldPathStep is a single step of a field path: a named field, a list index, or a wildcard
+// matching every field or item.
+type fieldPathStep struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// FieldPath is a parsed JSONPath style field selector supporting ".field", "['field']",
+// "[index]", ".*" and "[*]" steps, optionally starting with "$".
+type FieldPath struct {
+	path  string
+	steps []fieldPathStep
+	// validate, when set, checks each value resolved within the selected fields.
+	validate func(string) error
+	// expand is whether list entries within the selected fields which reference a StringList
+	// parameter in their entirety are expanded into an entry per item.
+	expand bool
+}
+
+func (p FieldPath) String() string {
+	return p.path
+}
+
+// UnmarshalJSON reads a field path given as a string, or as an object with a "path" and an
+// "expand" flag opting its lists into StringList expansion.
+func (p *FieldPath) UnmarshalJSON(data []byte) error {
+	var config struct {
+		Path   string `json:"path"`
+		Expand bool   `json:"expand"`
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&config); err != nil {
+			return err
+		}
+	} else if err := json.Unmarshal(data, &config.Path); err != nil {
+		return err
+	}
+
+	parsed, err := ParseFieldPath(config.Path)
+	if err != nil {
+		return err
+	}
+	parsed.expand = config.Expand
+	*p = parsed
+	return nil
+}
+
+// ParseFieldPath parses a field selector such as ".spec.subnetSelectorTerms[*].id".
+func ParseFieldPath(path string) (FieldPath, error) {
+	parsed := FieldPath{path: path}
+	invalid := func(reason string) (FieldPath, error) {
+		return FieldPath{}, fmt.Errorf("invalid field path %q: %s", path, reason)
+	}
+
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	if rest != "" && rest[0] != '.' && rest[0] != '[' {
+		rest = "." + rest
+	}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[") + 1
+			if end == 0 {
+				end = len(rest)
+			}
+			field := rest[1:end]
+			switch field {
+			case "":
+				return invalid("empty field name")
+			case "*":
+				parsed.steps = append(parsed.steps, fieldPathStep{wildcard: true})
+			default:
+				parsed.steps = append(parsed.steps, fieldPathStep{field: field})
+			}
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return invalid("unterminated [")
+			}
+			selector := rest[1:end]
+			switch {
+			case selector == "*":
+				parsed.steps = append(parsed.steps, fieldPathStep{wildcard: true})
+			case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+				parsed.steps = append(parsed.steps, fieldPathStep{field: selector[1 : len(selector)-1]})
+			default:
+				index, err := strconv.Atoi(selector)
+				if err != nil || index < 0 {
+					return invalid(fmt.Sprintf("unsupported selector [%s]", selector))
+				}
+				parsed.steps = append(parsed.steps, fieldPathStep{index: index, isIndex: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return invalid(fmt.Sprintf("unexpected %q", rest[0]))
+		}
+	}
+
+	if len(parsed.steps) == 0 {
+		return invalid("no fields selected")
+	}
+	return parsed, nil
+}
+
+// mustParseFieldPath parses a built-in field path of a kind, whose resolved values are checked
+// with validate if it is not nil and whose lists are expanded if expand is set.
+func mustParseFieldPath(path string, validate func(string) error, expand bool) FieldPath {
+	fieldPath, err := ParseFieldPath(path)
+	if err != nil {
+		panic(err)
+	}
+	fieldPath.validate, fieldPath.expand = validate, expand
+	return fieldPath
+}
+
+// mustParseFieldPaths parses the built-in field paths of a kind, each relative to the prefix.
+func mustParseFieldPaths(prefix string, paths []string) []FieldPath {
+	fieldPaths := make([]FieldPath, 0, len(paths))
+	for _, path := range paths {
+		fieldPaths = append(fieldPaths, mustParseFieldPath(prefix+path, nil, false))
+	}
+	return fieldPaths
+}
+
+// collectFieldPath searches every value selected by the field path for SSM Parameter
+// references. Selected maps and lists are searched in their entirety.
+func collectFieldPath(refs *parameterReferences, kind string, object map[string]interface{}, path FieldPath) {
+	var walk func(value interface{}, steps []fieldPathStep, location string, set func(interface{}))
+	walk = func(value interface{}, steps []fieldPathStep, location string, set func(interface{})) {
+		if len(steps) == 0 {
+			collectUnstructured(refs, kind, value, location, path.validate, path.expand, set)
+			return
+		}
+
+		step := steps[0]
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			if step.isIndex {
+				return
+			}
+			for key, child := range typed {
+				if step.wildcard || key == step.field {
+					walk(child, steps[1:], location+"."+key, func(v interface{}) { typed[key] = v })
+				}
+			}
+		case []interface{}:
+			if !step.isIndex && !step.wildcard {
+				return
+			}
+			for i, child := range typed {
+				if step.wildcard || i == step.index {
+					walk(child, steps[1:], fmt.Sprintf("%s[%d]", location, i), func(v interface{}) { typed[i] = v })
+				}
+			}
+		}
+	}
+
+	walk(object, path.steps, "", nil)
+}
+
+// collectUnstructured searches the value, and everything within it, for SSM Parameter references,
+// checking resolved values with validate if it is not nil. When expand is set, a string within a
+// list which references a StringList parameter in its entirety is expanded into an entry per item.
+func collectUnstructured(
+	refs *parameterReferences,
+	kind string,
+	value interface{},
+	location string,
+	validate func(string) error,
+	expand bool,
+	set func(interface{}),
+) {
+	switch typed := value.(type) {
+	case string:
+		refs.addValidated(kind+" field "+location, typed, validate, func(paramValue string) {
+			set(paramValue)
+		})
+	case map[string]interface{}:
+		for key, child := range typed {
+			collectUnstructured(refs, kind, child, location+"."+key, validate, expand, func(v interface{}) {
+				typed[key] = v
+			})
+		}
+	case []interface{}:
+		// The list is rebuilt from the entries of every item whenever one of them changes, so that
+		// the changes of every item are kept once any of them has been expanded.
+		expanded := make([][]interface{}, len(typed))
+		for i, child := range typed {
+			expanded[i] = []interface{}{child}
+			childLocation := fmt.Sprintf("%s[%d]", location, i)
+			if child, ok := child.(string); ok && expand {
+				refs.addList(kind+" field "+childLocation, child, validate, func(paramValues []string) {
+					expanded[i] = make([]interface{}, len(paramValues))
+					for j, paramValue := range paramValues {
+						expanded[i][j] = paramValue
+					}
+					set(slices.Concat(expanded...))
+				})
+				continue
+			}
+			collectUnstructured(refs, kind, child, childLocation, validate, expand, func(v interface{}) {
+				expanded[i] = []interface{}{v}
+				set(slices.Concat(expanded...))
+			})
+		}
+	}
+}
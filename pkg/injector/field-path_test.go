@@ -0,0 +1,183 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// resolveFieldPath resolves the references within the object's fields selected by the path.
+func resolveFieldPath(t *testing.T, object map[string]interface{}, path FieldPath) map[string]interface{} {
+	t.Helper()
+
+	s := newTestInjector(fakeProvider{
+		"/app/a":    {Value: "a"},
+		"/app/b":    {Value: "b"},
+		"/app/list": {Value: "x, y", Type: types.ParameterTypeStringList},
+		"/app/more": {Value: "z", Type: types.ParameterTypeStringList},
+	})
+	refs := &parameterReferences{}
+	collectFieldPath(refs, "Test", object, path)
+	if _, err := s.injectParameters(context.Background(), refs); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return object
+}
+
+func parseTestObject(t *testing.T, content string) map[string]interface{} {
+	t.Helper()
+
+	object := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(content), &object); err != nil {
+		t.Fatalf("invalid test object: %s", err)
+	}
+	return object
+}
+
+func TestCollectFieldPathStringList(t *testing.T) {
+	tests := []struct {
+		name     string
+		object   string
+		path     string
+		expand   bool
+		expected string
+	}{
+		{
+			name:     "not expanded",
+			object:   `{"spec": {"hosts": ["ssm://app/list", "ssm://app/a"]}}`,
+			path:     ".spec.hosts",
+			expected: `{"spec": {"hosts": ["x, y", "a"]}}`,
+		},
+		{
+			name:     "expanded",
+			object:   `{"spec": {"hosts": ["ssm://app/list", "ssm://app/a"]}}`,
+			path:     ".spec.hosts",
+			expand:   true,
+			expected: `{"spec": {"hosts": ["x", "y", "a"]}}`,
+		},
+		{
+			name:     "two expanded entries in one list",
+			object:   `{"spec": {"hosts": ["ssm://app/list", "static", "ssm://app/more", "ssm://app/b"]}}`,
+			path:     ".spec.hosts",
+			expand:   true,
+			expected: `{"spec": {"hosts": ["x", "y", "static", "z", "b"]}}`,
+		},
+		{
+			name:     "nested list after an expanded entry",
+			object:   `{"spec": {"groups": ["ssm://app/list", ["ssm://app/more", "ssm://app/a"], {"name": "ssm://app/b"}]}}`,
+			path:     ".spec.groups",
+			expand:   true,
+			expected: `{"spec": {"groups": ["x", "y", ["z", "a"], {"name": "b"}]}}`,
+		},
+		{
+			name:     "nested list before an expanded entry",
+			object:   `{"spec": {"groups": [["ssm://app/more", "ssm://app/a"], "ssm://app/list"]}}`,
+			path:     ".spec.groups",
+			expand:   true,
+			expected: `{"spec": {"groups": [["z", "a"], "x", "y"]}}`,
+		},
+		{
+			name:     "lists selected within a list",
+			object:   `{"spec": {"rules": [{"hosts": ["ssm://app/list"]}, {"hosts": ["ssm://app/more", "ssm://app/list"]}]}}`,
+			path:     ".spec.rules[*].hosts",
+			expand:   true,
+			expected: `{"spec": {"rules": [{"hosts": ["x", "y"]}, {"hosts": ["z", "x", "y"]}]}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := ParseFieldPath(test.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			path.expand = test.expand
+
+			resolved := resolveFieldPath(t, parseTestObject(t, test.object), path)
+			if expected := parseTestObject(t, test.expected); !equality.Semantic.DeepEqual(resolved, expected) {
+				resolvedJson, _ := json.Marshal(resolved)
+				t.Errorf("expected %s, got %s", test.expected, resolvedJson)
+			}
+		})
+	}
+}
+
+func TestLoadFieldPathRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		expand []bool
+		err    bool
+	}{
+		{
+			name: "paths",
+			config: "rules:\n- group: example.com\n  kind: Widget\n  fieldPaths:\n  - .spec.host\n" +
+				"  - path: .spec.hosts\n    expand: true\n",
+			expand: []bool{false, true},
+		},
+		{
+			name:   "unknown field path option",
+			config: "rules:\n- group: example.com\n  kind: Widget\n  fieldPaths:\n  - path: .spec.hosts\n    expnad: true\n",
+			err:    true,
+		},
+		{
+			name:   "invalid field path",
+			config: "rules:\n- group: example.com\n  kind: Widget\n  fieldPaths:\n  - path: .spec[\n",
+			err:    true,
+		},
+		{
+			name:   "missing field paths",
+			config: "rules:\n- group: example.com\n  kind: Widget\n",
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "field-paths.yaml")
+			if err := os.WriteFile(path, []byte(test.config), 0o600); err != nil {
+				t.Fatalf("unable to write field paths config: %s", err)
+			}
+
+			rules, err := LoadFieldPathRules(path)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", rules)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(rules) != 1 || len(rules[0].FieldPaths) != len(test.expand) {
+				t.Fatalf("expected one rule with %d field paths, got %v", len(test.expand), rules)
+			}
+			for i, fieldPath := range rules[0].FieldPaths {
+				if fieldPath.expand != test.expand[i] {
+					t.Errorf("expected field path %s to have expand %t", fieldPath, test.expand[i])
+				}
+			}
+		})
+	}
+}
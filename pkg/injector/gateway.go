@@ -0,0 +1,53 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"context"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const gatewayAPIGroup = "gateway.networking.k8s.io"
+
+var (
+	// gatewayFieldPaths select the fields of a Gateway which may reference SSM Parameters.
+	gatewayFieldPaths = []FieldPath{
+		mustParseFieldPath(".spec.gatewayClassName", validator(validation.IsDNS1123Subdomain), false),
+		mustParseFieldPath(".spec.listeners[*].hostname", validateHostname, false),
+		mustParseFieldPath(".spec.listeners[*].tls.certificateRefs[*].name",
+			validator(validation.IsDNS1123Subdomain), false),
+	}
+	// routeFieldPaths select the fields of an HTTPRoute or GRPCRoute which may reference SSM
+	// Parameters. Hostnames referencing a StringList parameter are expanded into a hostname per item.
+	routeFieldPaths = []FieldPath{
+		mustParseFieldPath(".spec.hostnames", validateHostname, true),
+		mustParseFieldPath(".spec.rules[*].backendRefs[*].name", validator(validation.IsDNS1123Subdomain), false),
+	}
+)
+
+// handleGatewayAPI searches Gateways, HTTPRoutes and GRPCRoutes of the Gateway API for SSM Parameter references.
+func (s *SSMParameterInjector) handleGatewayAPI(ctx context.Context, req admission.Request) admission.Response {
+	fieldPaths := slices.Clone(routeFieldPaths)
+	if req.Kind.Kind == "Gateway" {
+		fieldPaths = slices.Clone(gatewayFieldPaths)
+	}
+
+	return s.injectUnstructured(ctx, req, append(fieldPaths, s.configuredFieldPaths(req)...))
+}
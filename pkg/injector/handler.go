@@ -18,45 +18,92 @@ package injector
 
 import (
 	"context"
-	"fmt"
-	"net/http"
 
-	"github.com/aws/aws-sdk-go-v2/service/ssm"
-	_ "github.com/aws/aws-sdk-go-v2/service/ssm"
+	"aedificans.com/k8s-ssm-param-injector/pkg/provider"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
+var (
+	externalSecretsV1      = schema.GroupVersion{Group: "external-secrets.io", Version: "v1"}
+	externalSecretsV1beta1 = schema.GroupVersion{Group: "external-secrets.io", Version: "v1beta1"}
+	gatewayAPIV1           = schema.GroupVersion{Group: gatewayAPIGroup, Version: "v1"}
+)
+
 type SSMParameterInjector struct {
-	SsmClient *ssm.Client
-	Decoder   admission.Decoder
+	Provider provider.Provider
+	Client   client.Client
+	Decoder  admission.Decoder
+	// FieldPathRules select the fields searched for references in kinds without a dedicated handler.
+	FieldPathRules []FieldPathRule
+	// EnableSecretRefs allows workloads to opt into injecting SecureString values through
+	// generated Secrets, which requires permission to create Secrets.
+	EnableSecretRefs bool
 }
 
 func (s *SSMParameterInjector) Handle(ctx context.Context, req admission.Request) admission.Response {
-	switch req.Kind.Kind {
-	case "ConfigMap":
+	// Kinds are matched on their group and version too, so that a custom resource sharing the name of
+	// a built-in kind, such as a Knative Service, is handled as unstructured.
+	switch schema.GroupVersionKind(req.Kind) {
+	case externalSecretsV1.WithKind("ClusterExternalSecret"), externalSecretsV1beta1.WithKind("ClusterExternalSecret"):
+		log.Log.WithValues("action", req.Operation).Info("ClusterExternalSecret request received")
+		return s.handleExternalSecret(ctx, req)
+	case corev1.SchemeGroupVersion.WithKind("ConfigMap"):
 		log.Log.WithValues("action", req.Operation).Info("ConfigMap request received")
 		return s.handleConfigMap(ctx, req)
-	case "CronJob":
+	case batchv1.SchemeGroupVersion.WithKind("CronJob"):
 		log.Log.WithValues("action", req.Operation).Info("CronJob request received")
 		return s.handleCronJob(ctx, req)
-	case "ExternalSecret":
+	case appsv1.SchemeGroupVersion.WithKind("DaemonSet"):
+		log.Log.WithValues("action", req.Operation).Info("DaemonSet request received")
+		return s.handleDaemonSet(ctx, req)
+	case appsv1.SchemeGroupVersion.WithKind("Deployment"):
+		log.Log.WithValues("action", req.Operation).Info("Deployment request received")
+		return s.handleDeployment(ctx, req)
+	case externalSecretsV1.WithKind("ExternalSecret"), externalSecretsV1beta1.WithKind("ExternalSecret"):
 		log.Log.WithValues("action", req.Operation).Info("ExternalSecret request received")
 		return s.handleExternalSecret(ctx, req)
-	case "Ingress":
+	case gatewayAPIV1.WithKind("GRPCRoute"):
+		log.Log.WithValues("action", req.Operation).Info("GRPCRoute request received")
+		return s.handleGatewayAPI(ctx, req)
+	case gatewayAPIV1.WithKind("Gateway"):
+		log.Log.WithValues("action", req.Operation).Info("Gateway request received")
+		return s.handleGatewayAPI(ctx, req)
+	case gatewayAPIV1.WithKind("HTTPRoute"):
+		log.Log.WithValues("action", req.Operation).Info("HTTPRoute request received")
+		return s.handleGatewayAPI(ctx, req)
+	case networkingv1.SchemeGroupVersion.WithKind("Ingress"):
 		log.Log.WithValues("action", req.Operation).Info("Ingress request received")
 		return s.handleIngress(ctx, req)
-	case "Job":
+	case batchv1.SchemeGroupVersion.WithKind("Job"):
 		log.Log.WithValues("action", req.Operation).Info("Job request received")
 		return s.handleJob(ctx, req)
-	case "Pod":
-		log.Log.WithValues("action", req.Operation).Info("Pod request received")
+	case corev1.SchemeGroupVersion.WithKind("Pod"):
+		log.Log.WithValues("action", req.Operation, "subResource", req.SubResource).Info("Pod request received")
 		return s.handlePod(ctx, req)
-	case "ServiceAccount":
+	case appsv1.SchemeGroupVersion.WithKind("ReplicaSet"):
+		log.Log.WithValues("action", req.Operation).Info("ReplicaSet request received")
+		return s.handleReplicaSet(ctx, req)
+	case corev1.SchemeGroupVersion.WithKind("Secret"):
+		log.Log.WithValues("action", req.Operation).Info("Secret request received")
+		return s.handleSecret(ctx, req)
+	case corev1.SchemeGroupVersion.WithKind("Service"):
+		log.Log.WithValues("action", req.Operation).Info("Service request received")
+		return s.handleService(ctx, req)
+	case corev1.SchemeGroupVersion.WithKind("ServiceAccount"):
 		log.Log.WithValues("action", req.Operation).Info("ServiceAccount request received")
 		return s.handleServiceAccount(ctx, req)
+	case appsv1.SchemeGroupVersion.WithKind("StatefulSet"):
+		log.Log.WithValues("action", req.Operation).Info("StatefulSet request received")
+		return s.handleStatefulSet(ctx, req)
 	default:
-		log.Log.WithValues("action", req.Operation).Error(nil, "unsupported Kind")
-		return admission.Errored(http.StatusBadRequest, fmt.Errorf("unsupported Kind: %s", req.Kind.Kind))
+		log.Log.WithValues("action", req.Operation, "kind", req.Kind.Kind).Info("Unstructured request received")
+		return s.handleUnstructured(ctx, req)
 	}
 }
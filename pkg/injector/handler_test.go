@@ -0,0 +1,352 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"aedificans.com/k8s-ssm-param-injector/pkg/provider"
+)
+
+// fakeProvider serves parameters from a map keyed by their names, including any selector.
+type fakeProvider map[string]provider.Parameter
+
+func (p fakeProvider) GetParameters(_ context.Context, names []string) (map[string]provider.Parameter, error) {
+	params := map[string]provider.Parameter{}
+	for _, name := range names {
+		if param, ok := p[name]; ok {
+			params[name] = param
+		}
+	}
+	return params, nil
+}
+
+func (p fakeProvider) GetParametersByPath(_ context.Context, path string) (map[string]provider.Parameter, error) {
+	params := map[string]provider.Parameter{}
+	for name, param := range p {
+		if strings.HasPrefix(name, strings.TrimSuffix(path, "/")+"/") {
+			params[name] = param
+		}
+	}
+	return params, nil
+}
+
+func newTestInjector(params fakeProvider, objects ...client.Object) *SSMParameterInjector {
+	return &SSMParameterInjector{
+		Provider: params,
+		Client:   fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(objects...).Build(),
+		Decoder:  admission.NewDecoder(clientgoscheme.Scheme),
+	}
+}
+
+// admit sends the object to the injector in a request with the operation, decoding the object
+// with the response's patches applied into patched.
+func admit(t *testing.T, s *SSMParameterInjector, operation admissionv1.Operation, obj client.Object,
+	patched any) admission.Response {
+	t.Helper()
+	return admitUpdate(t, s, operation, nil, obj, patched)
+}
+
+// admitUpdate is admit for a request which also holds the object as it was before the update.
+func admitUpdate(t *testing.T, s *SSMParameterInjector, operation admissionv1.Operation, oldObj client.Object,
+	obj client.Object, patched any) admission.Response {
+	t.Helper()
+	return admitSubResource(t, s, operation, "", oldObj, obj, patched)
+}
+
+// admitSubResource is admitUpdate for a request made to a subresource of the object, such as
+// pods/ephemeralcontainers.
+func admitSubResource(t *testing.T, s *SSMParameterInjector, operation admissionv1.Operation, subResource string,
+	oldObj client.Object, obj client.Object, patched any) admission.Response {
+	t.Helper()
+
+	gvk, err := apiutil.GVKForObject(obj, clientgoscheme.Scheme)
+	if err != nil {
+		t.Fatalf("unable to find the kind of %T: %s", obj, err)
+	}
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("unable to marshal %T: %s", obj, err)
+	}
+	var oldRaw []byte
+	if oldObj != nil {
+		if oldRaw, err = json.Marshal(oldObj); err != nil {
+			t.Fatalf("unable to marshal %T: %s", oldObj, err)
+		}
+	}
+
+	resp := s.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Kind:        metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind},
+		Namespace:   obj.GetNamespace(),
+		Operation:   operation,
+		SubResource: subResource,
+		Object:      runtime.RawExtension{Raw: raw},
+		OldObject:   runtime.RawExtension{Raw: oldRaw},
+	}})
+	if !resp.Allowed {
+		return resp
+	}
+
+	if len(resp.Patches) > 0 {
+		patch, err := json.Marshal(resp.Patches)
+		if err != nil {
+			t.Fatalf("unable to marshal patches: %s", err)
+		}
+		decoded, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			t.Fatalf("unable to decode patches: %s", err)
+		}
+		if raw, err = decoded.Apply(raw); err != nil {
+			t.Fatalf("unable to apply patches: %s", err)
+		}
+	}
+	if err := json.Unmarshal(raw, patched); err != nil {
+		t.Fatalf("unable to unmarshal patched %T: %s", obj, err)
+	}
+	return resp
+}
+
+// failingProvider fails to retrieve the named parameter or path, as when access to it is denied.
+type failingProvider struct {
+	provider.Provider
+	name string
+}
+
+func (p failingProvider) GetParameters(ctx context.Context, names []string) (map[string]provider.Parameter, error) {
+	if slices.Contains(names, p.name) {
+		return nil, fmt.Errorf("access denied to %s", p.name)
+	}
+	return p.Provider.GetParameters(ctx, names)
+}
+
+func (p failingProvider) GetParametersByPath(ctx context.Context, path string) (map[string]provider.Parameter, error) {
+	if path == p.name {
+		return nil, fmt.Errorf("access denied to %s", p.name)
+	}
+	return p.Provider.GetParametersByPath(ctx, path)
+}
+
+func TestHandlePod(t *testing.T) {
+	s := newTestInjector(fakeProvider{
+		"/app/db/host":     {Value: "db.local"},
+		"/app/db/password": {Value: "hunter2", Type: types.ParameterTypeSecureString},
+		"/app/image":       {Value: "registry.local/app:1.2.3"},
+		"/app/env/LEVEL":   {Value: "debug"},
+	})
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app",
+			Namespace:   "default",
+			Annotations: map[string]string{envFromPathAnnotation: "/app/env"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  "app",
+				Image: "ssm://app/image",
+				Args:  []string{"--host=${ssm://app/db/host}"},
+				Env: []corev1.EnvVar{
+					{Name: "DB_HOST", Value: "ssm://app/db/host"},
+					{Name: "DB_PASSWORD", Value: "ssm://app/db/password"},
+					{Name: "PLAIN", Value: "value"},
+				},
+			}},
+		},
+	}
+
+	patched := &corev1.Pod{}
+	resp := admit(t, s, admissionv1.Create, pod, patched)
+	if !resp.Allowed {
+		t.Fatalf("expected the Pod to be allowed, got %v", resp.Result)
+	}
+
+	container := patched.Spec.Containers[0]
+	if container.Image != "registry.local/app:1.2.3" {
+		t.Errorf("expected the image to be resolved, got %q", container.Image)
+	}
+	if !slices.Equal(container.Args, []string{"--host=db.local"}) {
+		t.Errorf("expected the args to be resolved, got %q", container.Args)
+	}
+	expected := map[string]string{"DB_HOST": "db.local", "DB_PASSWORD": "hunter2", "PLAIN": "value", "LEVEL": "debug"}
+	env := map[string]string{}
+	for _, envVar := range container.Env {
+		env[envVar.Name] = envVar.Value
+	}
+	if !maps.Equal(env, expected) {
+		t.Errorf("expected env %v, got %v", expected, env)
+	}
+}
+
+func TestHandlePodEphemeralContainers(t *testing.T) {
+	s := newTestInjector(fakeProvider{
+		"/app/db/host":     {Value: "db.local"},
+		"/app/debug/image": {Value: "registry.local/debug:1.0.0"},
+	})
+	// kubectl debug adds an ephemeral container to a running Pod through the subresource.
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "registry.local/app:1.2.3"}},
+		},
+	}
+	oldPod := pod.DeepCopy()
+	pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:  "debugger",
+			Image: "ssm://app/debug/image",
+			Args:  []string{"--host=${ssm://app/db/host}"},
+			Env:   []corev1.EnvVar{{Name: "DB_HOST", Value: "ssm://app/db/host"}},
+		},
+	}}
+
+	patched := &corev1.Pod{}
+	resp := admitSubResource(t, s, admissionv1.Update, "ephemeralcontainers", oldPod, pod, patched)
+	if !resp.Allowed {
+		t.Fatalf("expected the Pod to be allowed, got %v", resp.Result)
+	}
+
+	container := patched.Spec.EphemeralContainers[0]
+	if container.Image != "registry.local/debug:1.0.0" {
+		t.Errorf("expected the image to be resolved, got %q", container.Image)
+	}
+	if !slices.Equal(container.Args, []string{"--host=db.local"}) {
+		t.Errorf("expected the args to be resolved, got %q", container.Args)
+	}
+	if len(container.Env) != 1 || container.Env[0].Value != "db.local" {
+		t.Errorf("expected the env to be resolved, got %+v", container.Env)
+	}
+}
+
+func TestHandlePodNotFound(t *testing.T) {
+	s := newTestInjector(fakeProvider{})
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "app",
+				Env:  []corev1.EnvVar{{Name: "DB_HOST", Value: "ssm://app/db/host"}},
+			}},
+		},
+	}
+
+	resp := admit(t, s, admissionv1.Create, pod, &corev1.Pod{})
+	if resp.Allowed || resp.Result.Code != http.StatusBadRequest {
+		t.Errorf("expected the Pod to be rejected as a bad request, got %v", resp.Result)
+	}
+}
+
+func TestHandleWithFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "parameters.yaml")
+	content := "/app/db/host: db.local\napp/db/port: \"5432\"\n/app/env/LEVEL: debug\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("unable to write parameters file: %s", err)
+	}
+	fileProvider, err := provider.NewFileProvider(path)
+	if err != nil {
+		t.Fatalf("unable to create file provider: %s", err)
+	}
+	s := newTestInjector(nil)
+	s.Provider = fileProvider
+
+	// Names are matched with or without their leading "/", as they are by SSM.
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app",
+			Namespace:   "default",
+			Annotations: map[string]string{envFromPathAnnotation: "app/env"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "app",
+				Env: []corev1.EnvVar{
+					{Name: "DB_HOST", Value: "ssm:/app/db/host"},
+					{Name: "DB_PORT", Value: "ssm://app/db/port"},
+				},
+			}},
+		},
+	}
+
+	patched := &corev1.Pod{}
+	resp := admit(t, s, admissionv1.Create, pod, patched)
+	if !resp.Allowed {
+		t.Fatalf("expected the Pod to be allowed, got %v", resp.Result)
+	}
+
+	expected := map[string]string{"DB_HOST": "db.local", "DB_PORT": "5432", "LEVEL": "debug"}
+	env := map[string]string{}
+	for _, envVar := range patched.Spec.Containers[0].Env {
+		env[envVar.Name] = envVar.Value
+	}
+	if !maps.Equal(env, expected) {
+		t.Errorf("expected env %v, got %v", expected, env)
+	}
+}
+
+func TestHandleCustomResourceWithBuiltInKind(t *testing.T) {
+	rule, err := ParseFieldPathRule("Service.serving.knative.dev=.spec.template.spec.containers[*].image")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	s := newTestInjector(fakeProvider{"/app/image": {Value: "registry.local/app:1.2.3"}})
+	s.FieldPathRules = []FieldPathRule{rule}
+
+	// A Knative Service shares its kind with the core Service, so must not be decoded as one.
+	service := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "serving.knative.dev/v1",
+		"kind":       "Service",
+		"metadata":   map[string]any{"name": "app", "namespace": "default"},
+		"spec": map[string]any{
+			"template": map[string]any{
+				"spec": map[string]any{
+					"containers": []any{map[string]any{"image": "ssm://app/image"}},
+				},
+			},
+		},
+	}}
+
+	patched := &unstructured.Unstructured{}
+	resp := admit(t, s, admissionv1.Create, service, patched)
+	if !resp.Allowed {
+		t.Fatalf("expected the Service to be allowed, got %v", resp.Result)
+	}
+
+	containers, _, _ := unstructured.NestedSlice(patched.Object, "spec", "template", "spec", "containers")
+	if len(containers) != 1 || containers[0].(map[string]any)["image"] != "registry.local/app:1.2.3" {
+		t.Errorf("expected the image to be resolved, got %v", containers)
+	}
+}
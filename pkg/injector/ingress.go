@@ -20,9 +20,10 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"strings"
+	"slices"
 
 	networkingV1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -39,31 +40,19 @@ func (s *SSMParameterInjector) handleIngress(ctx context.Context, req admission.
 	log.Log.WithValues("name", ingress.Name, "namespace", ingress.Namespace).
 		V(1).Info("Ingress successfully decoded")
 
-	hasUpdatedAnnotations := false
-	if ingress.Annotations != nil {
-		hasUpdatedAnnotations, err = s.processAnnotations(ctx, ingress.Annotations)
-		if err != nil {
-			return admission.Errored(http.StatusInternalServerError, err)
-		}
-	}
-
-	hasUpdatedRuleHosts := false
-	if ingress.Spec.Rules != nil {
-		hasUpdatedRuleHosts, err = s.processIngressRules(ctx, ingress)
-		if err != nil {
-			return admission.Errored(http.StatusInternalServerError, err)
-		}
-	}
+	refs := &parameterReferences{}
+	collectObjectMeta(refs, &ingress.ObjectMeta)
+	collectIngressRules(refs, ingress)
+	collectIngressTLS(refs, ingress)
+	collectIngressBackends(refs, ingress)
+	collectIngressClassName(refs, ingress)
 
-	hasUpdatedTLSHosts := false
-	if ingress.Spec.TLS != nil {
-		hasUpdatedTLSHosts, err = s.processIngressTLS(ctx, ingress)
-		if err != nil {
-			return admission.Errored(http.StatusInternalServerError, err)
-		}
+	wasModified, err := s.injectParameters(ctx, refs)
+	if err != nil {
+		return admission.Errored(errorCode(err), err)
 	}
 
-	if !hasUpdatedAnnotations && !hasUpdatedRuleHosts && !hasUpdatedTLSHosts {
+	if !wasModified {
 		log.Log.Info("No SSM parameters found")
 		return admission.Allowed("No modifications required")
 	}
@@ -75,53 +64,86 @@ func (s *SSMParameterInjector) handleIngress(ctx context.Context, req admission.
 	}
 
 	log.Log.Info("Returning JSON patch for value injection(s)")
-	return admission.PatchResponseFromRaw(req.Object.Raw, ingressJson)
+	return admission.PatchResponseFromRaw(req.Object.Raw, ingressJson).WithWarnings(refs.warnings...)
 }
 
-func (s *SSMParameterInjector) processIngressRules(ctx context.Context, ingress *networkingV1.Ingress) (bool, error) {
-	wasModified := false
-
-	for i, rule := range ingress.Spec.Rules {
-		if strings.HasPrefix(rule.Host, "ssm:/") {
-			log.Log.Info("SSM Parameter detected in Ingress rule")
-			log.Log.WithValues("paramKey", rule.Host).
-				V(1).Info("SSM Parameter detected")
-			paramName := strings.TrimPrefix(rule.Host, "ssm:/")
-			paramValue, err := s.getSSMParameter(ctx, paramName)
-			if err != nil {
-				return false, err
+// expandRulesAnnotation opts an Ingress into cloning each rule whose host references a
+// StringList parameter once per host, so that one parameter can drive a multi-domain Ingress.
+const expandRulesAnnotation = annotationPrefix + "expand-rules"
+
+func collectIngressRules(refs *parameterReferences, ingress *networkingV1.Ingress) {
+	if ingress.Annotations[expandRulesAnnotation] == "true" {
+		collectExpandedIngressRules(refs, ingress)
+		return
+	}
+
+	for i := range ingress.Spec.Rules {
+		rule := &ingress.Spec.Rules[i]
+		refs.addValidated("Ingress rule hostname", rule.Host, validateHostname, func(paramValue string) {
+			rule.Host = paramValue
+		})
+	}
+}
+
+// collectExpandedIngressRules searches the rules' hosts, replacing a rule whose host references a
+// StringList parameter with a copy of it for each host. The copies share the rule's paths, so
+// that references within them are resolved for every copy.
+func collectExpandedIngressRules(refs *parameterReferences, ingress *networkingV1.Ingress) {
+	rules := ingress.Spec.Rules
+	expanded := make([][]networkingV1.IngressRule, len(rules))
+	for i := range rules {
+		expanded[i] = []networkingV1.IngressRule{rules[i]}
+		refs.addList("Ingress rule hostname", rules[i].Host, validateHostname, func(paramValues []string) {
+			expanded[i] = make([]networkingV1.IngressRule, len(paramValues))
+			for j, host := range paramValues {
+				expanded[i][j] = rules[i]
+				expanded[i][j].Host = host
 			}
-			log.Log.V(1).Info("Updating Ingress rule hostname with SSM Parameter value")
-			ingress.Spec.Rules[i].Host = paramValue
-			wasModified = true
-		}
+			ingress.Spec.Rules = slices.Concat(expanded...)
+		})
 	}
+}
 
-	return wasModified, nil
+func collectIngressTLS(refs *parameterReferences, ingress *networkingV1.Ingress) {
+	for i := range ingress.Spec.TLS {
+		tls := &ingress.Spec.TLS[i]
+		collectStringList(refs, "Ingress TLS host", &tls.Hosts, validateHostname)
+		refs.addValidated("Ingress TLS secret name", tls.SecretName, validator(validation.IsDNS1123Subdomain),
+			func(paramValue string) {
+				tls.SecretName = paramValue
+			})
+	}
 }
 
-func (s *SSMParameterInjector) processIngressTLS(ctx context.Context, ingress *networkingV1.Ingress) (bool, error) {
-	wasModified := false
-
-	for i, tls := range ingress.Spec.TLS {
-		if tls.Hosts != nil {
-			for j, host := range tls.Hosts {
-				if strings.HasPrefix(host, "ssm:/") {
-					log.Log.Info("SSM Parameter detected in Ingress TLS hosts")
-					log.Log.WithValues("paramKey", host).
-						V(1).Info("SSM Parameter detected")
-					paramName := strings.TrimPrefix(host, "ssm:/")
-					paramValue, err := s.getSSMParameter(ctx, paramName)
-					if err != nil {
-						return false, err
-					}
-					log.Log.V(1).Info("Updating Ingress TLS host with SSM Parameter value")
-					ingress.Spec.TLS[i].Hosts[j] = paramValue
-					wasModified = true
-				}
+// collectIngressBackends searches the service names of the default backend and of every rule's paths.
+func collectIngressBackends(refs *parameterReferences, ingress *networkingV1.Ingress) {
+	collectIngressBackend(refs, ingress.Spec.DefaultBackend)
+	for i := range ingress.Spec.Rules {
+		if ruleValue := ingress.Spec.Rules[i].HTTP; ruleValue != nil {
+			for j := range ruleValue.Paths {
+				collectIngressBackend(refs, &ruleValue.Paths[j].Backend)
 			}
 		}
 	}
+}
 
-	return wasModified, nil
+func collectIngressBackend(refs *parameterReferences, backend *networkingV1.IngressBackend) {
+	if backend == nil || backend.Service == nil {
+		return
+	}
+	service := backend.Service
+	refs.addValidated("Ingress backend service name", service.Name, validator(validation.IsDNS1035Label),
+		func(paramValue string) {
+			service.Name = paramValue
+		})
+}
+
+func collectIngressClassName(refs *parameterReferences, ingress *networkingV1.Ingress) {
+	if ingress.Spec.IngressClassName == nil {
+		return
+	}
+	refs.addValidated("Ingress class name", *ingress.Spec.IngressClassName, validator(validation.IsDNS1123Subdomain),
+		func(paramValue string) {
+			ingress.Spec.IngressClassName = &paramValue
+		})
 }
@@ -0,0 +1,233 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	admissionv1 "k8s.io/api/admission/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"aedificans.com/k8s-ssm-param-injector/pkg/provider"
+)
+
+func newIngressBackend(serviceName string) networkingv1.IngressBackend {
+	return networkingv1.IngressBackend{
+		Service: &networkingv1.IngressServiceBackend{
+			Name: serviceName,
+			Port: networkingv1.ServiceBackendPort{Number: 80},
+		},
+	}
+}
+
+func newIngress(host string) *networkingv1.Ingress {
+	defaultBackend := newIngressBackend("ssm://app/default-service")
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: ptr.To("ssm://app/ingress-class"),
+			DefaultBackend:   &defaultBackend,
+			TLS: []networkingv1.IngressTLS{{
+				Hosts:      []string{host},
+				SecretName: "ssm://app/tls-secret",
+			}},
+			Rules: []networkingv1.IngressRule{{
+				Host: host,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:     "/",
+							PathType: ptr.To(networkingv1.PathTypePrefix),
+							Backend:  newIngressBackend("ssm://app/service"),
+						}},
+					},
+				},
+			}},
+		},
+	}
+}
+
+func newIngressTestInjector(params fakeProvider) *SSMParameterInjector {
+	params["/app/default-service"] = provider.Parameter{Value: "fallback"}
+	params["/app/ingress-class"] = provider.Parameter{Value: "nginx"}
+	params["/app/tls-secret"] = provider.Parameter{Value: "app-tls"}
+	params["/app/service"] = provider.Parameter{Value: "web"}
+	return newTestInjector(params)
+}
+
+func TestHandleIngress(t *testing.T) {
+	s := newIngressTestInjector(fakeProvider{"/app/host": {Value: "app.example.com"}})
+
+	patched := &networkingv1.Ingress{}
+	resp := admit(t, s, admissionv1.Create, newIngress("ssm://app/host"), patched)
+	if !resp.Allowed {
+		t.Fatalf("expected the Ingress to be allowed, got %v", resp.Result)
+	}
+
+	if className := ptr.Deref(patched.Spec.IngressClassName, ""); className != "nginx" {
+		t.Errorf("expected the class name to be resolved, got %q", className)
+	}
+	if name := patched.Spec.DefaultBackend.Service.Name; name != "fallback" {
+		t.Errorf("expected the default backend to be resolved, got %q", name)
+	}
+	tls := patched.Spec.TLS[0]
+	if tls.SecretName != "app-tls" || !slices.Equal(tls.Hosts, []string{"app.example.com"}) {
+		t.Errorf("expected the TLS secret name and hosts to be resolved, got %+v", tls)
+	}
+	rule := patched.Spec.Rules[0]
+	if rule.Host != "app.example.com" {
+		t.Errorf("expected the rule host to be resolved, got %q", rule.Host)
+	}
+	if name := rule.HTTP.Paths[0].Backend.Service.Name; name != "web" {
+		t.Errorf("expected the backend to be resolved, got %q", name)
+	}
+}
+
+func TestHandleIngressInvalidValues(t *testing.T) {
+	s := newIngressTestInjector(fakeProvider{
+		"/app/host":          {Value: "app.example.com"},
+		"/app/invalid-host":  {Value: "App_Example.com"},
+		"/app/invalid-name":  {Value: "Web.Service"},
+		"/app/invalid-class": {Value: "nginx/internal"},
+	})
+
+	tests := []struct {
+		name   string
+		modify func(ingress *networkingv1.Ingress)
+	}{
+		{
+			name: "hostname",
+			modify: func(ingress *networkingv1.Ingress) {
+				ingress.Spec.Rules[0].Host = "ssm://app/invalid-host"
+			},
+		},
+		{
+			name: "TLS host",
+			modify: func(ingress *networkingv1.Ingress) {
+				ingress.Spec.TLS[0].Hosts = []string{"ssm://app/invalid-host"}
+			},
+		},
+		{
+			name: "backend service name",
+			modify: func(ingress *networkingv1.Ingress) {
+				ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name = "ssm://app/invalid-name"
+			},
+		},
+		{
+			name: "class name",
+			modify: func(ingress *networkingv1.Ingress) {
+				ingress.Spec.IngressClassName = ptr.To("ssm://app/invalid-class")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ingress := newIngress("ssm://app/host")
+			test.modify(ingress)
+
+			resp := admit(t, s, admissionv1.Create, ingress, &networkingv1.Ingress{})
+			if resp.Allowed || resp.Result.Code != http.StatusBadRequest {
+				t.Errorf("expected the Ingress to be rejected as a bad request, got %v", resp.Result)
+			}
+		})
+	}
+}
+
+func TestHandleIngressStringList(t *testing.T) {
+	s := newIngressTestInjector(fakeProvider{
+		"/app/host":  {Value: "app.example.com"},
+		"/app/hosts": {Value: "a.example.com,b.example.com", Type: ssmtypes.ParameterTypeStringList},
+		"/app/gappy": {Value: "a.example.com,, b.example.com,", Type: ssmtypes.ParameterTypeStringList},
+	})
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		tlsHosts    []string
+		ruleHost    string
+		expected    []string
+		// expectedRules are the hosts of the rules, each of which must keep the rule's paths.
+		expectedRules []string
+	}{
+		{
+			name:          "TLS hosts expanded",
+			tlsHosts:      []string{"ssm://app/hosts", "static.example.com", "ssm://app/host"},
+			ruleHost:      "ssm://app/host",
+			expected:      []string{"a.example.com", "b.example.com", "static.example.com", "app.example.com"},
+			expectedRules: []string{"app.example.com"},
+		},
+		{
+			name:          "empty list elements skipped",
+			tlsHosts:      []string{"ssm://app/gappy"},
+			ruleHost:      "ssm://app/host",
+			expected:      []string{"a.example.com", "b.example.com"},
+			expectedRules: []string{"app.example.com"},
+		},
+		{
+			name:          "rules cloned per host",
+			annotations:   map[string]string{expandRulesAnnotation: "true"},
+			tlsHosts:      []string{"ssm://app/hosts"},
+			ruleHost:      "ssm://app/hosts",
+			expected:      []string{"a.example.com", "b.example.com"},
+			expectedRules: []string{"a.example.com", "b.example.com"},
+		},
+		{
+			name:          "rules cloned per host with empty list elements",
+			annotations:   map[string]string{expandRulesAnnotation: "true"},
+			tlsHosts:      []string{"ssm://app/gappy"},
+			ruleHost:      "ssm://app/gappy",
+			expected:      []string{"a.example.com", "b.example.com"},
+			expectedRules: []string{"a.example.com", "b.example.com"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ingress := newIngress(test.ruleHost)
+			ingress.Annotations = test.annotations
+			ingress.Spec.TLS[0].Hosts = test.tlsHosts
+			// A second rule which is not expanded keeps its place after the cloned rules.
+			ingress.Spec.Rules = append(ingress.Spec.Rules, networkingv1.IngressRule{Host: "other.example.com"})
+
+			patched := &networkingv1.Ingress{}
+			resp := admit(t, s, admissionv1.Create, ingress, patched)
+			if !resp.Allowed {
+				t.Fatalf("expected the Ingress to be allowed, got %v", resp.Result)
+			}
+
+			if hosts := patched.Spec.TLS[0].Hosts; !slices.Equal(hosts, test.expected) {
+				t.Errorf("expected TLS hosts %q, got %q", test.expected, hosts)
+			}
+			var ruleHosts []string
+			for _, rule := range patched.Spec.Rules {
+				ruleHosts = append(ruleHosts, rule.Host)
+			}
+			if expected := append(test.expectedRules, "other.example.com"); !slices.Equal(ruleHosts, expected) {
+				t.Errorf("expected rule hosts %q, got %q", expected, ruleHosts)
+			}
+			for _, rule := range patched.Spec.Rules[:len(test.expectedRules)] {
+				if rule.HTTP == nil || rule.HTTP.Paths[0].Backend.Service.Name != "web" {
+					t.Errorf("expected the rule for %s to keep its resolved paths, got %+v", rule.Host, rule.HTTP)
+				}
+			}
+		})
+	}
+}
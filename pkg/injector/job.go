@@ -38,23 +38,19 @@ func (s *SSMParameterInjector) handleJob(ctx context.Context, req admission.Requ
 	log.Log.WithValues("name", job.Name, "namespace", job.Namespace).
 		V(1).Info("Job successfully decoded")
 
-	hasUpdatedContainers := false
-	if job.Spec.Template.Spec.Containers != nil {
-		hasUpdatedContainers, err = s.processContainers(ctx, job.Spec.Template.Spec.Containers)
-		if err != nil {
-			return admission.Errored(http.StatusInternalServerError, err)
-		}
+	refs := &parameterReferences{
+		secret: s.newGeneratedSecret(req, &job.ObjectMeta, &job.Spec.Template.ObjectMeta),
 	}
+	collectObjectMeta(refs, &job.ObjectMeta)
+	collectLabelSelector(refs, job.Spec.Selector)
+	collectPodTemplate(refs, &job.Spec.Template)
 
-	hasUpdatedInitContainers := false
-	if job.Spec.Template.Spec.InitContainers != nil {
-		hasUpdatedInitContainers, err = s.processContainers(ctx, job.Spec.Template.Spec.InitContainers)
-		if err != nil {
-			return admission.Errored(http.StatusInternalServerError, err)
-		}
+	wasModified, err := s.injectParameters(ctx, refs)
+	if err != nil {
+		return admission.Errored(errorCode(err), err)
 	}
 
-	if !hasUpdatedContainers && !hasUpdatedInitContainers {
+	if !wasModified {
 		log.Log.Info("No SSM parameters found")
 		return admission.Allowed("No modifications required")
 	}
@@ -66,5 +62,5 @@ func (s *SSMParameterInjector) handleJob(ctx context.Context, req admission.Requ
 	}
 
 	log.Log.Info("Returning JSON patch for value injection(s)")
-	return admission.PatchResponseFromRaw(req.Object.Raw, jobJson)
+	return admission.PatchResponseFromRaw(req.Object.Raw, jobJson).WithWarnings(refs.warnings...)
 }
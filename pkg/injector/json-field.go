@@ -0,0 +1,78 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// fieldSeparator separates a parameter reference from the path of the field to extract from
+// the parameter's JSON value, e.g. "ssm:/app/db#username" or "ssm:/app/db#hosts[0].name".
+const fieldSeparator = "#"
+
+// parseFieldPath compiles the path following fieldSeparator as a JSONPath expression. Paths
+// are relative to the root of the document unless they start with "$".
+func parseFieldPath(fieldPath string) (*jsonpath.JSONPath, error) {
+	if fieldPath == "" {
+		return nil, fmt.Errorf("missing field path after %q", fieldSeparator)
+	}
+
+	expression := fieldPath
+	if !strings.HasPrefix(expression, "$") && !strings.HasPrefix(expression, ".") {
+		expression = "." + expression
+	}
+
+	parser := jsonpath.New(fieldPath).AllowMissingKeys(false)
+	if err := parser.Parse("{" + expression + "}"); err != nil {
+		return nil, fmt.Errorf("invalid field path %q: %s", fieldPath, err)
+	}
+
+	return parser, nil
+}
+
+// extractField returns the field of the parameter's JSON value selected by the reference's
+// field path. String fields are returned as is and any other fields as JSON.
+func (p *parameterReference) extractField(value string) (string, error) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(value), &document); err != nil {
+		return "", fmt.Errorf("SSM Parameter %s is not a JSON document, unable to extract field %q",
+			p.key(), p.fieldPath)
+	}
+
+	results, err := p.fieldParser.FindResults(document)
+	if err != nil {
+		return "", fmt.Errorf("field %q not found in SSM Parameter %s: %s", p.fieldPath, p.key(), err)
+	}
+	if len(results) != 1 || len(results[0]) != 1 {
+		return "", fmt.Errorf("field %q does not select a single value in SSM Parameter %s", p.fieldPath, p.key())
+	}
+
+	field := results[0][0].Interface()
+	if fieldString, ok := field.(string); ok {
+		return fieldString, nil
+	}
+	fieldJson, err := json.Marshal(field)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal field %q of SSM Parameter %s: %s", p.fieldPath, p.key(), err)
+	}
+
+	return string(fieldJson), nil
+}
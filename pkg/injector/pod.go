@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -38,23 +39,21 @@ func (s *SSMParameterInjector) handlePod(ctx context.Context, req admission.Requ
 	log.Log.WithValues("name", pod.Name, "namespace", pod.Namespace).
 		V(1).Info("Pod successfully decoded")
 
-	hasUpdatedContainers := false
-	if pod.Spec.Containers != nil {
-		hasUpdatedContainers, err = s.processContainers(ctx, pod.Spec.Containers)
-		if err != nil {
-			return admission.Errored(http.StatusInternalServerError, err)
-		}
+	refs := &parameterReferences{secret: s.newGeneratedSecret(req, &pod.ObjectMeta, nil)}
+	collectObjectMeta(refs, &pod.ObjectMeta)
+	collectPodSpec(refs, &pod.Spec)
+	// The env of a Pod's containers cannot be changed once it exists, and a Pod created from a pod
+	// template which was already injected inherits its generated env vars.
+	if _, inherited := pod.Annotations[envFromPathGeneratedAnnotation]; req.Operation != admissionv1.Update && !inherited {
+		collectEnvFromPath(refs, &pod.ObjectMeta, &pod.Spec)
 	}
 
-	hasUpdatedInitContainers := false
-	if pod.Spec.InitContainers != nil {
-		hasUpdatedInitContainers, err = s.processContainers(ctx, pod.Spec.InitContainers)
-		if err != nil {
-			return admission.Errored(http.StatusInternalServerError, err)
-		}
+	wasModified, err := s.injectParameters(ctx, refs)
+	if err != nil {
+		return admission.Errored(errorCode(err), err)
 	}
 
-	if !hasUpdatedContainers && !hasUpdatedInitContainers {
+	if !wasModified {
 		log.Log.Info("No SSM parameters found")
 		return admission.Allowed("No modifications required")
 	}
@@ -66,5 +65,5 @@ func (s *SSMParameterInjector) handlePod(ctx context.Context, req admission.Requ
 	}
 
 	log.Log.Info("Returning JSON patch for value injection(s)")
-	return admission.PatchResponseFromRaw(req.Object.Raw, podJson)
+	return admission.PatchResponseFromRaw(req.Object.Raw, podJson).WithWarnings(refs.warnings...)
 }
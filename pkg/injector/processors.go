@@ -18,76 +18,277 @@ package injector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"slices"
 	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"aedificans.com/k8s-ssm-param-injector/pkg/provider"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-func (s *SSMParameterInjector) processAnnotations(ctx context.Context, annotations map[string]string) (bool, error) {
-	wasModified := false
+// imageReferencePattern matches an image reference of the form
+// [registry[:port]/]path[:tag][@digest], following the grammar of the distribution project.
+var imageReferencePattern = regexp.MustCompile(
+	`^(?:(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])(?:\.(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9]))*` +
+		`(?::[0-9]+)?/)?` +
+		`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+		`(?::[\w][\w.-]{0,127})?` +
+		`(?:@[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9A-Fa-f]{32,})?$`)
 
+func collectObjectMeta(refs *parameterReferences, objectMeta *metav1.ObjectMeta) {
+	collectAnnotations(refs, objectMeta.Annotations)
+	collectLabels(refs, objectMeta.Labels)
+}
+
+func collectAnnotations(refs *parameterReferences, annotations map[string]string) {
 	for key, value := range annotations {
-		if strings.HasPrefix(value, "ssm:/") {
-			log.Log.Info("SSM Parameter detected in annotation")
-			log.Log.WithValues("paramKey", value).
-				V(1).Info("SSM Parameter detected")
-			paramName := strings.TrimPrefix(value, "ssm:/")
-			paramValue, err := s.getSSMParameter(ctx, paramName)
-			if err != nil {
-				return false, err
-			}
-			log.Log.V(1).Info("Updating annotation with SSM Parameter value")
+		if isSystemAnnotation(key) {
+			continue
+		}
+		refs.add("annotation "+key, value, func(paramValue string) {
 			annotations[key] = paramValue
-			wasModified = true
+		})
+	}
+}
+
+// isSystemAnnotation returns whether the annotation is reserved for Kubernetes components, which
+// are never searched. kubectl.kubernetes.io/last-applied-configuration, for one, holds a copy of
+// the whole resource, so resolving it would leak the values of SecureString parameters.
+func isSystemAnnotation(key string) bool {
+	prefix, _, found := strings.Cut(key, "/")
+	if !found {
+		return false
+	}
+	for _, domain := range []string{"kubernetes.io", "k8s.io"} {
+		if prefix == domain || strings.HasSuffix(prefix, "."+domain) {
+			return true
 		}
 	}
+	return false
+}
 
-	return wasModified, nil
+func collectLabels(refs *parameterReferences, labels map[string]string) {
+	for key, value := range labels {
+		refs.addValidated("label "+key, value, validateLabelValue, func(paramValue string) {
+			labels[key] = paramValue
+		})
+	}
 }
 
-func (s *SSMParameterInjector) processContainers(ctx context.Context, containers []corev1.Container) (bool, error) {
-	wasModified := false
+// collectLabelSelector searches the values of a workload's selector, which resolve to the same
+// values as the labels of its pod template which reference the same parameters.
+func collectLabelSelector(refs *parameterReferences, selector *metav1.LabelSelector) {
+	if selector == nil {
+		return
+	}
+	for key, value := range selector.MatchLabels {
+		refs.addValidated("selector label "+key, value, validateLabelValue, func(paramValue string) {
+			selector.MatchLabels[key] = paramValue
+		})
+	}
+	for i := range selector.MatchExpressions {
+		expression := &selector.MatchExpressions[i]
+		for j, value := range expression.Values {
+			refs.addValidated("selector expression "+expression.Key, value, validateLabelValue,
+				func(paramValue string) {
+					expression.Values[j] = paramValue
+				})
+		}
+	}
+}
 
-	for i, container := range containers {
-		for j, envVar := range container.Env {
-			if strings.HasPrefix(envVar.Value, "ssm:/") {
-				log.Log.Info("SSM Parameter detected in container environment variable value")
-				log.Log.WithValues("paramKey", envVar.Value).
-					V(1).Info("SSM Parameter detected")
-				paramName := strings.TrimPrefix(envVar.Value, "ssm:/")
-				paramValue, err := s.getSSMParameter(ctx, paramName)
-				if err != nil {
-					return false, err
-				}
-				log.Log.V(1).Info("Updating container environment variable value with SSM Parameter value")
-				containers[i].Env[j].Value = paramValue
-				wasModified = true
-			}
+var validateLabelValue = validator(validation.IsValidLabelValue)
+
+// validator adapts a validation function returning error messages to a field's validate function.
+func validator(validate func(string) []string) func(string) error {
+	return func(value string) error {
+		if errs := validate(value); len(errs) > 0 {
+			return errors.New(strings.Join(errs, ", "))
 		}
+		return nil
 	}
+}
 
-	return wasModified, nil
+// validateHostname checks the value is a valid DNS-1123 hostname, which may be a wildcard such
+// as "*.example.com".
+func validateHostname(value string) error {
+	if strings.HasPrefix(value, "*.") {
+		return validator(validation.IsWildcardDNS1123Subdomain)(value)
+	}
+	return validator(validation.IsDNS1123Subdomain)(value)
 }
 
-func (s *SSMParameterInjector) getSSMParameter(ctx context.Context, paramName string) (string, error) {
-	WithDecryption := true
-	ssmRequestInput := &ssm.GetParameterInput{
-		Name:           &paramName,
-		WithDecryption: &WithDecryption,
+func collectPodTemplate(refs *parameterReferences, template *corev1.PodTemplateSpec) {
+	collectObjectMeta(refs, &template.ObjectMeta)
+	collectPodSpec(refs, &template.Spec)
+	collectEnvFromPath(refs, &template.ObjectMeta, &template.Spec)
+}
+
+func collectPodSpec(refs *parameterReferences, podSpec *corev1.PodSpec) {
+	collectContainers(refs, podSpec.Containers)
+	collectContainers(refs, podSpec.InitContainers)
+	collectEphemeralContainers(refs, podSpec.EphemeralContainers)
+}
+
+func collectEphemeralContainers(refs *parameterReferences, containers []corev1.EphemeralContainer) {
+	for i := range containers {
+		container := &containers[i].EphemeralContainerCommon
+		collectContainer(refs, container.Name, &container.Image, container.Command, container.Args, container.Env)
 	}
+}
 
-	log.Log.WithValues("paramName", paramName).V(1).Info("Retrieving SSM Parameter value")
-	ssmResponse, err := s.SsmClient.GetParameter(ctx, ssmRequestInput)
-	if err != nil {
-		log.Log.WithValues("paramName", paramName).Error(err, "failed to retrieve SSM parameter")
-		return "", fmt.Errorf("failed to retrieve SSM parameter: %s", err)
+func collectImage(refs *parameterReferences, image *string) {
+	refs.addValidated("container image", *image, validateImage, func(paramValue string) {
+		*image = paramValue
+	})
+}
+
+// validateImage checks the value is a valid container image reference, which may be pinned
+// to a digest as well as, or instead of, a tag.
+func validateImage(value string) error {
+	if !imageReferencePattern.MatchString(value) {
+		return fmt.Errorf("%q is not a valid image reference", value)
+	}
+	if name, _, _ := strings.Cut(value, "@"); len(name) > 255 {
+		return fmt.Errorf("%q is longer than 255 characters", name)
+	}
+	return nil
+}
+
+func collectStrings(refs *parameterReferences, field string, values []string) {
+	for i := range values {
+		refs.add(field, values[i], func(paramValue string) {
+			values[i] = paramValue
+		})
+	}
+}
+
+// collectStringList searches the entries of a list-valued field, expanding each entry which
+// references a StringList parameter into an entry per item.
+func collectStringList(refs *parameterReferences, field string, values *[]string, validate func(string) error) {
+	expanded := make([][]string, len(*values))
+	for i, value := range *values {
+		expanded[i] = []string{value}
+		refs.addList(field, value, validate, func(paramValues []string) {
+			expanded[i] = paramValues
+			*values = slices.Concat(expanded...)
+		})
+	}
+}
+
+func collectContainers(refs *parameterReferences, containers []corev1.Container) {
+	for i := range containers {
+		container := &containers[i]
+		collectContainer(refs, container.Name, &container.Image, container.Command, container.Args, container.Env)
+	}
+}
+
+// collectContainer searches the fields shared by containers and ephemeral containers.
+func collectContainer(
+	refs *parameterReferences,
+	name string,
+	image *string,
+	command []string,
+	args []string,
+	env []corev1.EnvVar,
+) {
+	collectImage(refs, image)
+	collectStrings(refs, "container command", command)
+	collectStrings(refs, "container args", args)
+	for i := range env {
+		envVar := &env[i]
+		refs.addSecretRef("container environment variable value", envVar.Value, func(paramValue string) {
+			envVar.Value = paramValue
+		}, func(paramValue string) {
+			envVar.Value = ""
+			envVar.ValueFrom = &corev1.EnvVarSource{
+				SecretKeyRef: refs.secret.add(name+"."+envVar.Name, paramValue),
+			}
+		})
+	}
+}
+
+// injectParameters retrieves the values of every collected SSM Parameter and applies them to
+// the object they were collected from, returning whether any values were injected.
+func (s *SSMParameterInjector) injectParameters(ctx context.Context, refs *parameterReferences) (bool, error) {
+	if err := refs.err(); err != nil {
+		return false, err
+	}
+	if refs.isEmpty() {
+		return false, nil
+	}
+
+	paramValues := map[string]provider.Parameter{}
+	if paramNames := refs.names(); len(paramNames) > 0 {
+		var err error
+		paramValues, err = s.Provider.GetParameters(ctx, paramNames)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for _, field := range refs.fields {
+		value, isSecure, err := refs.render(&field, paramValues)
+		if err != nil {
+			log.Log.Error(err, "unable to render "+field.field)
+			return false, err
+		}
+		values := []string{value}
+		if field.applyList != nil && field.isStringList(paramValues) {
+			values = splitStringList(value)
+		}
+		if field.validate != nil {
+			for _, value := range values {
+				if err := field.validate(value); err != nil {
+					log.Log.Error(err, "invalid SSM Parameter value for "+field.field)
+					return false, &referenceError{
+						err: fmt.Errorf("invalid SSM Parameter value for %s: %s", field.field, err),
+					}
+				}
+			}
+		}
+		if field.applyList != nil {
+			log.Log.V(1).Info(fmt.Sprintf("Updating %s with %d SSM Parameter value(s)", field.field, len(values)))
+			field.applyList(values)
+			continue
+		}
+		if field.applySecretRef != nil && isSecure {
+			log.Log.V(1).Info("Moving " + field.field + " SecureString value into generated Secret")
+			field.applySecretRef(value)
+			continue
+		}
+		log.Log.V(1).Info("Updating " + field.field + " with SSM Parameter value")
+		field.apply(value)
+	}
+
+	for _, path := range refs.paths {
+		pathValues := make(map[string]map[string]provider.Parameter, len(path.paths))
+		for _, paramPath := range path.paths {
+			paramValues, err := s.Provider.GetParametersByPath(ctx, paramPath)
+			if err != nil {
+				return false, err
+			}
+			if len(paramValues) == 0 {
+				log.Log.WithValues("path", paramPath).Info("No SSM Parameters found beneath path for " + path.field)
+				refs.warnings = append(refs.warnings,
+					fmt.Sprintf("no SSM Parameters found beneath path %s for %s", paramPath, path.field))
+			}
+			pathValues[paramPath] = paramValues
+		}
+		log.Log.V(1).Info("Updating " + path.field + " with SSM Parameter values")
+		path.apply(pathValues)
+	}
+
+	if refs.secret != nil {
+		if err := s.createGeneratedSecret(ctx, refs.secret); err != nil {
+			return false, err
+		}
 	}
 
-	log.Log.WithValues("paramValue", *ssmResponse.Parameter.Value).
-		V(2).Info("SSM Parameter retrieved value")
-	log.Log.V(1).Info("Returning retrieved SSM Parameter value")
-	return *ssmResponse.Parameter.Value, nil
+	return true, nil
 }
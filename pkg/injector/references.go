@@ -0,0 +1,427 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"aedificans.com/k8s-ssm-param-injector/pkg/provider"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	ssmParameterPrefix = "ssm:/"
+	// placeholderPrefix opens an SSM Parameter reference embedded in a larger value, which is
+	// closed by placeholderSuffix, e.g. "postgres://${ssm:/app/db/user}@host/db".
+	placeholderPrefix = "${" + ssmParameterPrefix
+	placeholderSuffix = "}"
+	// escapedPlaceholder is written in place of a literal "${" in values containing placeholders.
+	escapedPlaceholder = "$${"
+	// optionsSeparator separates a reference from its options, e.g. "ssm:/app/flag?default=false".
+	optionsSeparator = "?"
+	// defaultOption is the value to inject when the parameter does not exist.
+	defaultOption = "default"
+)
+
+// selectorPattern matches a parameter version number or label.
+var selectorPattern = regexp.MustCompile(`^([0-9]+|[a-zA-Z_.-][a-zA-Z0-9_.-]*)$`)
+
+// parameterReference is a reference to an SSM Parameter within a field's value.
+type parameterReference struct {
+	name string
+	// selector is the version number or label of the parameter to retrieve, if any.
+	selector string
+	// fieldPath selects a single field of the parameter's JSON value to inject, if any.
+	fieldPath   string
+	fieldParser *jsonpath.JSONPath
+	// defaultValue is injected when the parameter does not exist, if hasDefault is set.
+	defaultValue string
+	hasDefault   bool
+}
+
+// parseReference parses the reference following the prefix or within a placeholder, which is
+// the parameter name optionally followed by a ":<version>" or ":<label>" selector, then a
+// "#<field path>" and finally "?<options>". Names may be ARNs which contain colons, so only a
+// colon after the name's final "/" starts a selector.
+func parseReference(reference string) (*parameterReference, error) {
+	ref := &parameterReference{}
+
+	if i := indexOutsideBrackets(reference, optionsSeparator); i >= 0 {
+		if err := ref.parseOptions(reference[i+len(optionsSeparator):]); err != nil {
+			return nil, err
+		}
+		reference = reference[:i]
+	}
+
+	if i := strings.Index(reference, fieldSeparator); i >= 0 {
+		fieldParser, err := parseFieldPath(reference[i+len(fieldSeparator):])
+		if err != nil {
+			return nil, err
+		}
+		ref.fieldPath, ref.fieldParser = reference[i+len(fieldSeparator):], fieldParser
+		reference = reference[:i]
+	}
+	ref.name = reference
+
+	nameStart := strings.LastIndex(reference, "/") + 1
+	if i := strings.LastIndex(reference[nameStart:], ":"); i >= 0 {
+		ref.name = reference[:nameStart+i]
+		ref.selector = reference[nameStart+i+1:]
+		if !selectorPattern.MatchString(ref.selector) {
+			return nil, fmt.Errorf("invalid version or label %q for SSM Parameter %s", ref.selector, ref.name)
+		}
+	}
+
+	if ref.name == "" {
+		return nil, fmt.Errorf("missing SSM Parameter name in %q", reference)
+	}
+
+	return ref, nil
+}
+
+// indexOutsideBrackets returns the index of the first separator which is not within square
+// brackets, so that the filter expressions of field paths do not start a reference's options.
+func indexOutsideBrackets(reference string, separator string) int {
+	depth := 0
+	for i := 0; i < len(reference); i++ {
+		switch {
+		case reference[i] == '[':
+			depth++
+		case reference[i] == ']' && depth > 0:
+			depth--
+		case depth == 0 && strings.HasPrefix(reference[i:], separator):
+			return i
+		}
+	}
+	return -1
+}
+
+// parseOptions parses the URL query encoded options of a reference.
+func (p *parameterReference) parseOptions(options string) error {
+	values, err := url.ParseQuery(options)
+	if err != nil {
+		return fmt.Errorf("invalid options %q: %s", options, err)
+	}
+
+	for option, optionValues := range values {
+		switch option {
+		case defaultOption:
+			p.defaultValue, p.hasDefault = optionValues[len(optionValues)-1], true
+		default:
+			return fmt.Errorf("unknown option %q", option)
+		}
+	}
+
+	return nil
+}
+
+// key is the name, including any selector, which the parameter is retrieved and cached by.
+func (p *parameterReference) key() string {
+	if p.selector == "" {
+		return p.name
+	}
+	return p.name + ":" + p.selector
+}
+
+// valueSegment is a piece of a field's value which is either literal text or a reference.
+type valueSegment struct {
+	literal string
+	ref     *parameterReference
+}
+
+// fieldReference is a field whose value references SSM Parameters, along with the function
+// used to write the resolved value back into the object.
+type fieldReference struct {
+	field    string
+	segments []valueSegment
+	apply    func(value string)
+	// validate, when set, checks the resolved value is valid for the field before it is applied.
+	validate func(value string) error
+	// applySecretRef, when set, is used instead of apply for SecureString values so that
+	// they are injected through the generated Secret rather than in plaintext.
+	applySecretRef func(value string)
+	// applyList, when set, is used instead of apply for entries of list-valued fields, which are
+	// expanded into an entry per item of a StringList parameter.
+	applyList func(values []string)
+}
+
+// isStringList returns whether the field's value is a reference to a StringList parameter in
+// its entirety, without a JSON field selected from it.
+func (f *fieldReference) isStringList(paramValues map[string]provider.Parameter) bool {
+	if len(f.segments) != 1 || f.segments[0].ref == nil || f.segments[0].ref.fieldParser != nil {
+		return false
+	}
+	paramValue, found := paramValues[f.segments[0].ref.key()]
+	return found && paramValue.Type == types.ParameterTypeStringList
+}
+
+// splitStringList splits the value of a StringList parameter into its items.
+func splitStringList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// pathReference is a set of parameter paths whose parameters are injected together, along
+// with the function used to write them into the object.
+type pathReference struct {
+	field string
+	paths []string
+	// apply receives the parameters retrieved beneath each path, keyed by path.
+	apply func(pathValues map[string]map[string]provider.Parameter)
+}
+
+// parameterReferences collects every SSM Parameter reference found in an object so
+// that the values can be retrieved in batches before being injected.
+type parameterReferences struct {
+	fields   []fieldReference
+	paths    []pathReference
+	errs     []error
+	warnings []string
+	// secret is the generated Secret for SecureString values when secret-ref mode is enabled.
+	secret *generatedSecret
+}
+
+// referenceError is an error in the references of a resource, such as an invalid reference or
+// a parameter which does not exist, rather than a failure to retrieve the parameters.
+type referenceError struct {
+	err error
+}
+
+func (e *referenceError) Error() string {
+	return e.err.Error()
+}
+
+func (e *referenceError) Unwrap() error {
+	return e.err
+}
+
+// errorCode returns the status code a resource is rejected with for an error injecting its
+// parameters, so that mistakes in the resource are distinguished from failures of the provider.
+func errorCode(err error) int32 {
+	var refErr *referenceError
+	if errors.As(err, &refErr) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// hasReference returns whether the value is, or contains, an SSM Parameter reference.
+func hasReference(value string) bool {
+	return strings.HasPrefix(value, ssmParameterPrefix) || strings.Contains(value, placeholderPrefix)
+}
+
+// parseValue splits a value into literal text and SSM Parameter references. A value which
+// starts with ssmParameterPrefix is a reference in its entirety, otherwise any placeholders
+// within it are references. A placeholder ends at its first "}", so one within its options
+// must be URL encoded as "%7D".
+func parseValue(value string) ([]valueSegment, error) {
+	if strings.HasPrefix(value, ssmParameterPrefix) {
+		ref, err := parseReference(strings.TrimPrefix(value, ssmParameterPrefix))
+		if err != nil {
+			return nil, err
+		}
+		return []valueSegment{{ref: ref}}, nil
+	}
+
+	var segments []valueSegment
+	var literal strings.Builder
+	for rest := value; rest != ""; {
+		switch {
+		case strings.HasPrefix(rest, escapedPlaceholder):
+			literal.WriteString("${")
+			rest = rest[len(escapedPlaceholder):]
+		case strings.HasPrefix(rest, placeholderPrefix):
+			end := strings.Index(rest, placeholderSuffix)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated SSM Parameter placeholder in %q", value)
+			}
+			if literal.Len() > 0 {
+				segments = append(segments, valueSegment{literal: literal.String()})
+				literal.Reset()
+			}
+			ref, err := parseReference(rest[len(placeholderPrefix):end])
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, valueSegment{ref: ref})
+			rest = rest[end+len(placeholderSuffix):]
+		default:
+			literal.WriteByte(rest[0])
+			rest = rest[1:]
+		}
+	}
+	if literal.Len() > 0 {
+		segments = append(segments, valueSegment{literal: literal.String()})
+	}
+
+	return segments, nil
+}
+
+// add registers the field's value if it references any SSM Parameters.
+func (r *parameterReferences) add(field string, value string, apply func(string)) {
+	r.addField(value, fieldReference{field: field, apply: apply})
+}
+
+// addValidated registers the field's value if it references any SSM Parameters, checking the
+// resolved value with validate before it is applied.
+func (r *parameterReferences) addValidated(
+	field string,
+	value string,
+	validate func(string) error,
+	apply func(string),
+) {
+	r.addField(value, fieldReference{field: field, validate: validate, apply: apply})
+}
+
+// addSecretRef registers the field's value if it references any SSM Parameters, injecting
+// SecureString values with applySecretRef when secret-ref mode is enabled.
+func (r *parameterReferences) addSecretRef(
+	field string,
+	value string,
+	apply func(string),
+	applySecretRef func(string),
+) {
+	fieldRef := fieldReference{field: field, apply: apply}
+	if r.secret != nil {
+		fieldRef.applySecretRef = applySecretRef
+	}
+	r.addField(value, fieldRef)
+}
+
+// addList registers an entry of a list-valued field if it references any SSM Parameters. An
+// entry referencing a StringList parameter in its entirety is replaced by an entry per item,
+// each of which is checked with validate when it is set.
+func (r *parameterReferences) addList(
+	field string,
+	value string,
+	validate func(string) error,
+	applyList func([]string),
+) {
+	r.addField(value, fieldReference{field: field, validate: validate, applyList: applyList})
+}
+
+func (r *parameterReferences) addField(value string, fieldRef fieldReference) {
+	if !hasReference(value) {
+		return
+	}
+
+	log.Log.Info("SSM Parameter detected in " + fieldRef.field)
+	log.Log.WithValues("paramKey", value).
+		V(1).Info("SSM Parameter detected")
+	segments, err := parseValue(value)
+	if err != nil {
+		log.Log.Error(err, "unable to parse SSM Parameter reference in "+fieldRef.field)
+		r.errs = append(r.errs, fmt.Errorf("invalid %s: %s", fieldRef.field, err))
+		return
+	}
+
+	fieldRef.segments = segments
+	r.fields = append(r.fields, fieldRef)
+}
+
+// addPaths registers parameter paths whose parameters are retrieved and injected together.
+func (r *parameterReferences) addPaths(
+	field string,
+	paths []string,
+	apply func(map[string]map[string]provider.Parameter),
+) {
+	log.Log.Info("SSM Parameter path detected in " + field)
+	log.Log.WithValues("paths", paths).
+		V(1).Info("SSM Parameter path detected")
+	r.paths = append(r.paths, pathReference{field: field, paths: paths, apply: apply})
+}
+
+// err returns the errors encountered while collecting references.
+func (r *parameterReferences) err() error {
+	if len(r.errs) == 0 {
+		return nil
+	}
+	return &referenceError{err: errors.Join(r.errs...)}
+}
+
+// names returns the de-duplicated names, including selectors, of every collected SSM Parameter.
+func (r *parameterReferences) names() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, field := range r.fields {
+		for _, segment := range field.segments {
+			if segment.ref != nil && !seen[segment.ref.key()] {
+				seen[segment.ref.key()] = true
+				names = append(names, segment.ref.key())
+			}
+		}
+	}
+	return names
+}
+
+func (r *parameterReferences) isEmpty() bool {
+	return len(r.fields) == 0 && len(r.paths) == 0
+}
+
+// render builds the field's value from the retrieved parameter values, returning whether any
+// of the referenced parameters is a SecureString. Parameters which do not exist are replaced
+// by their default, with a warning, or are an error when they have none.
+func (r *parameterReferences) render(
+	field *fieldReference,
+	paramValues map[string]provider.Parameter,
+) (string, bool, error) {
+	var value strings.Builder
+	isSecure := false
+	for _, segment := range field.segments {
+		if segment.ref == nil {
+			value.WriteString(segment.literal)
+			continue
+		}
+
+		paramValue, found := paramValues[segment.ref.key()]
+		if !found {
+			if !segment.ref.hasDefault {
+				return "", false, &referenceError{err: fmt.Errorf("SSM Parameter %s not found", segment.ref.key())}
+			}
+			log.Log.WithValues("paramName", segment.ref.key()).
+				Info("SSM Parameter not found, injecting default value in " + field.field)
+			r.warnings = append(r.warnings,
+				fmt.Sprintf("SSM Parameter %s not found, using default value for %s", segment.ref.key(), field.field))
+			value.WriteString(segment.ref.defaultValue)
+			continue
+		}
+
+		refValue := paramValue.Value
+		if segment.ref.fieldParser != nil {
+			var err error
+			refValue, err = segment.ref.extractField(refValue)
+			if err != nil {
+				return "", false, &referenceError{err: fmt.Errorf("invalid %s: %s", field.field, err)}
+			}
+		}
+		value.WriteString(refValue)
+		isSecure = isSecure || paramValue.IsSecure()
+	}
+	return value.String(), isSecure, nil
+}
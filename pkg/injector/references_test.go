@@ -0,0 +1,206 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// describeSegments returns a comparable description of parsed segments.
+func describeSegments(segments []valueSegment) []string {
+	var described []string
+	for _, segment := range segments {
+		if segment.ref == nil {
+			described = append(described, "literal "+segment.literal)
+			continue
+		}
+		described = append(described, describeReference(segment.ref))
+	}
+	return described
+}
+
+func describeReference(ref *parameterReference) string {
+	described := fmt.Sprintf("name=%s selector=%s field=%s", ref.name, ref.selector, ref.fieldPath)
+	if ref.hasDefault {
+		described += " default=" + ref.defaultValue
+	}
+	return described
+}
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		reference string
+		expected  string
+		err       bool
+	}{
+		{reference: "/app/db/password", expected: "name=/app/db/password selector= field="},
+		{reference: "app/db/password", expected: "name=app/db/password selector= field="},
+		{reference: "password", expected: "name=password selector= field="},
+		{reference: "/app/db/password:3", expected: "name=/app/db/password selector=3 field="},
+		{reference: "/app/db/password:prod", expected: "name=/app/db/password selector=prod field="},
+		{reference: "/app:team/db/password", expected: "name=/app:team/db/password selector= field="},
+		{reference: "/app:team/db/password:prod", expected: "name=/app:team/db/password selector=prod field="},
+		{
+			reference: "arn:aws:ssm:us-east-1:123456789012:parameter/app/db/password",
+			expected:  "name=arn:aws:ssm:us-east-1:123456789012:parameter/app/db/password selector= field=",
+		},
+		{
+			reference: "arn:aws:ssm:us-east-1:123456789012:parameter/app/db/password:2",
+			expected:  "name=arn:aws:ssm:us-east-1:123456789012:parameter/app/db/password selector=2 field=",
+		},
+		{reference: "/app/db#.host", expected: "name=/app/db selector= field=.host"},
+		{reference: "/app/db:2#.host", expected: "name=/app/db selector=2 field=.host"},
+		{
+			reference: "/app/users#.users[?(@.name=='a?b')].id",
+			expected:  "name=/app/users selector= field=.users[?(@.name=='a?b')].id",
+		},
+		{
+			reference: "/app/users#.users[?(@.name=='a?b')].id?default=none",
+			expected:  "name=/app/users selector= field=.users[?(@.name=='a?b')].id default=none",
+		},
+		{reference: "/app/flag?default=false", expected: "name=/app/flag selector= field= default=false"},
+		{reference: "/app/flag?default=", expected: "name=/app/flag selector= field= default="},
+		{reference: "/app/url?default=a%26b%3Dc", expected: "name=/app/url selector= field= default=a&b=c"},
+		{reference: "/app/json?default=%7B%7D", expected: "name=/app/json selector= field= default={}"},
+		{reference: "/app/db/password:bad!", err: true},
+		{reference: "/app/db/password:", err: true},
+		{reference: ":3", err: true},
+		{reference: "/app/flag?unknown=1", err: true},
+		{reference: "/app/flag?default=%zz", err: true},
+		{reference: "/app/db#.users[", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.reference, func(t *testing.T) {
+			ref, err := parseReference(test.reference)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", describeReference(ref))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if described := describeReference(ref); described != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, described)
+			}
+		})
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+		err      bool
+	}{
+		{value: "ssm://app/db", expected: []string{"name=/app/db selector= field="}},
+		{value: "ssm:/app/db", expected: []string{"name=app/db selector= field="}},
+		{
+			value:    "ssm:/arn:aws:ssm:us-east-1:123456789012:parameter/app/db:1",
+			expected: []string{"name=arn:aws:ssm:us-east-1:123456789012:parameter/app/db selector=1 field="},
+		},
+		{
+			value: "postgres://${ssm://app/db/user}:${ssm://app/db/password:2}@host/db",
+			expected: []string{
+				"literal postgres://",
+				"name=/app/db/user selector= field=",
+				"literal :",
+				"name=/app/db/password selector=2 field=",
+				"literal @host/db",
+			},
+		},
+		{
+			value:    "${ssm://app/db#.host}:5432",
+			expected: []string{"name=/app/db selector= field=.host", "literal :5432"},
+		},
+		{
+			value:    "${ssm://app/db?default=localhost}",
+			expected: []string{"name=/app/db selector= field= default=localhost"},
+		},
+		{
+			value:    "${ssm://app/json?default=%7B%7D}",
+			expected: []string{"name=/app/json selector= field= default={}"},
+		},
+		{value: "$${ssm://app/db}", expected: []string{"literal ${ssm://app/db}"}},
+		{
+			value:    "$${literal} ${ssm://app/db}",
+			expected: []string{"literal ${literal} ", "name=/app/db selector= field="},
+		},
+		{value: "cost: $5", expected: []string{"literal cost: $5"}},
+		// A placeholder ends at its first "}", so one within a default must be encoded as %7D.
+		{
+			value:    "${ssm://app/json?default={}}",
+			expected: []string{"name=/app/json selector= field= default={", "literal }"},
+		},
+		{value: "${ssm://app/db", err: true},
+		{value: "${ssm://app/db:bad!}", err: true},
+		{value: "ssm://app/db?unknown=1", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			segments, err := parseValue(test.value)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", describeSegments(segments))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if described := describeSegments(segments); !slices.Equal(described, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, described)
+			}
+		})
+	}
+}
+
+func TestInjectParametersErrorCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected int32
+	}{
+		{name: "invalid reference", value: "ssm://app/db:bad!", expected: http.StatusBadRequest},
+		{name: "parameter not found", value: "ssm://app/missing", expected: http.StatusBadRequest},
+		{name: "unknown JSON field", value: "ssm://app/json#.missing", expected: http.StatusBadRequest},
+		{name: "provider failure", value: "ssm://app/failing", expected: http.StatusInternalServerError},
+	}
+
+	s := newTestInjector(fakeProvider{"/app/json": {Value: `{"host": "db"}`}})
+	s.Provider = failingProvider{Provider: s.Provider, name: "/app/failing"}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			refs := &parameterReferences{}
+			refs.add("test", test.value, func(string) {})
+			_, err := s.injectParameters(context.Background(), refs)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if code := errorCode(err); code != test.expected {
+				t.Errorf("expected status %d, got %d for %s", test.expected, code, err)
+			}
+		})
+	}
+}
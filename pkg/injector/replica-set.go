@@ -0,0 +1,66 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func (s *SSMParameterInjector) handleReplicaSet(ctx context.Context, req admission.Request) admission.Response {
+	replicaSet := &appsv1.ReplicaSet{}
+
+	log.Log.V(1).Info("Decoding ReplicaSet from request")
+	err := s.Decoder.Decode(req, replicaSet)
+	if err != nil {
+		log.Log.Error(err, "unable to decode ReplicaSet")
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	log.Log.WithValues("name", replicaSet.Name, "namespace", replicaSet.Namespace).
+		V(1).Info("ReplicaSet successfully decoded")
+
+	refs := &parameterReferences{
+		secret: s.newGeneratedSecret(req, &replicaSet.ObjectMeta, &replicaSet.Spec.Template.ObjectMeta),
+	}
+	collectObjectMeta(refs, &replicaSet.ObjectMeta)
+	collectLabelSelector(refs, replicaSet.Spec.Selector)
+	collectPodTemplate(refs, &replicaSet.Spec.Template)
+
+	wasModified, err := s.injectParameters(ctx, refs)
+	if err != nil {
+		return admission.Errored(errorCode(err), err)
+	}
+
+	if !wasModified {
+		log.Log.Info("No SSM parameters found")
+		return admission.Allowed("No modifications required")
+	}
+
+	replicaSetJson, err := json.Marshal(replicaSet)
+	if err != nil {
+		log.Log.Error(err, "unable to marshal modified ReplicaSet to JSON")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	log.Log.Info("Returning JSON patch for value injection(s)")
+	return admission.PatchResponseFromRaw(req.Object.Raw, replicaSetJson).WithWarnings(refs.warnings...)
+}
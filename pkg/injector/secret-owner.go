@@ -0,0 +1,117 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedBy      = "ssm-param-injector"
+	// pendingOwnerAnnotation records the workload a generated Secret was created for while the
+	// workload was being created, so that it can be made the Secret's owner once it exists.
+	pendingOwnerAnnotation = annotationPrefix + "pending-owner"
+
+	// pendingOwnerRetry is how often a generated Secret's pending owner is looked for.
+	pendingOwnerRetry = 5 * time.Second
+	// pendingOwnerTimeout is how long a generated Secret waits for its workload before being
+	// deleted, such as when the workload's creation was rejected after it was admitted.
+	pendingOwnerTimeout = 5 * time.Minute
+)
+
+// GeneratedSecretSelector selects the Secrets generated for SecureString values.
+var GeneratedSecretSelector = labels.SelectorFromSet(labels.Set{managedByLabel: managedBy})
+
+// GeneratedSecretReconciler sets the owner of the Secrets generated while admitting the creation
+// of their workloads, once the workloads exist and have UIDs.
+type GeneratedSecretReconciler struct {
+	Client client.Client
+	// APIReader reads workloads directly from the API server, so that they need not be watched.
+	APIReader client.Reader
+}
+
+func (r *GeneratedSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	hasPendingOwner := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		_, ok := obj.GetAnnotations()[pendingOwnerAnnotation]
+		return ok
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("generated-secret").
+		For(&corev1.Secret{}, builder.WithPredicates(hasPendingOwner)).
+		Complete(r)
+}
+
+func (r *GeneratedSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	secret := &corev1.Secret{}
+	if err := r.Client.Get(ctx, req.NamespacedName, secret); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	pendingOwner, ok := secret.Annotations[pendingOwnerAnnotation]
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+	logger := log.Log.WithValues("secretName", secret.Name, "namespace", secret.Namespace)
+
+	owner := metav1.OwnerReference{}
+	if err := json.Unmarshal([]byte(pendingOwner), &owner); err != nil {
+		logger.Error(err, "invalid pending owner of generated Secret")
+		return ctrl.Result{}, nil
+	}
+
+	workload := &metav1.PartialObjectMetadata{}
+	workload.SetGroupVersionKind(schema.FromAPIVersionAndKind(owner.APIVersion, owner.Kind))
+	err := r.APIReader.Get(ctx, client.ObjectKey{Namespace: secret.Namespace, Name: owner.Name}, workload)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	// A workload created before the Secret is another one of the same name, whose creation the
+	// Secret's workload failed on.
+	if apierrors.IsNotFound(err) || workload.CreationTimestamp.Before(&secret.CreationTimestamp) {
+		if age := time.Since(secret.CreationTimestamp.Time); age < pendingOwnerTimeout {
+			logger.V(1).Info("Workload of generated Secret does not exist yet", "workload", owner.Name)
+			return ctrl.Result{RequeueAfter: min(pendingOwnerRetry, pendingOwnerTimeout-age)}, nil
+		}
+		logger.Info("Workload of generated Secret was never created, deleting it", "workload", owner.Name)
+		return ctrl.Result{}, client.IgnoreNotFound(r.Client.Delete(ctx, secret))
+	}
+
+	patch := client.MergeFrom(secret.DeepCopy())
+	owner.UID = workload.UID
+	secret.OwnerReferences = append(secret.OwnerReferences, owner)
+	delete(secret.Annotations, pendingOwnerAnnotation)
+	if err := r.Client.Patch(ctx, secret, patch); err != nil {
+		logger.Error(err, "failed to set owner of generated Secret")
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Set owner of generated Secret", "workload", owner.Name)
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"context"
+	"testing"
+
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newSecretRefDeployment() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app",
+			Namespace:   "default",
+			Annotations: map[string]string{secretRefAnnotation: "true"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name: "app",
+						Env:  []corev1.EnvVar{{Name: "PASSWORD", Value: "ssm://app/password"}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// generatedSecretOf returns the only Secret generated by the injector.
+func generatedSecretOf(t *testing.T, s *SSMParameterInjector) *corev1.Secret {
+	t.Helper()
+
+	secrets := &corev1.SecretList{}
+	if err := s.Client.List(context.Background(), secrets, client.InNamespace("default")); err != nil {
+		t.Fatalf("unable to list Secrets: %s", err)
+	}
+	if len(secrets.Items) != 1 {
+		t.Fatalf("expected one generated Secret, got %d", len(secrets.Items))
+	}
+	return &secrets.Items[0]
+}
+
+func TestGeneratedSecretOwnerOnCreate(t *testing.T) {
+	s := newTestInjector(fakeProvider{"/app/password": {Value: "hunter2", Type: ssmtypes.ParameterTypeSecureString}})
+	s.EnableSecretRefs = true
+	reconciler := &GeneratedSecretReconciler{Client: s.Client, APIReader: s.Client}
+
+	deployment := newSecretRefDeployment()
+	patched := &appsv1.Deployment{}
+	if resp := admit(t, s, admissionv1.Create, deployment, patched); !resp.Allowed {
+		t.Fatalf("expected the Deployment to be allowed, got %v", resp.Result)
+	}
+	envVar := patched.Spec.Template.Spec.Containers[0].Env[0]
+	if envVar.Value != "" || envVar.ValueFrom == nil || envVar.ValueFrom.SecretKeyRef == nil {
+		t.Fatalf("expected PASSWORD to reference the generated Secret, got %+v", envVar)
+	}
+
+	secret := generatedSecretOf(t, s)
+	if len(secret.OwnerReferences) != 0 || secret.Annotations[pendingOwnerAnnotation] == "" {
+		t.Fatalf("expected the generated Secret to have a pending owner, got %+v", secret.ObjectMeta)
+	}
+
+	// The fake client does not set creation timestamps as the API server does.
+	secret.CreationTimestamp = metav1.Now()
+	if err := s.Client.Update(context.Background(), secret); err != nil {
+		t.Fatalf("unable to update Secret: %s", err)
+	}
+
+	// The Deployment has not been created yet, so the owner is looked for again later.
+	key := client.ObjectKeyFromObject(secret)
+	result, err := reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	if err != nil || result.RequeueAfter == 0 {
+		t.Fatalf("expected the generated Secret to be requeued, got %+v, %v", result, err)
+	}
+
+	patched.UID = types.UID("0b7a3c1e-4d2f-4e8a-9c6b-5f1d2e3a4b5c")
+	patched.CreationTimestamp = metav1.Now()
+	if err := s.Client.Create(context.Background(), patched); err != nil {
+		t.Fatalf("unable to create Deployment: %s", err)
+	}
+	if _, err := reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("unable to reconcile generated Secret: %s", err)
+	}
+
+	secret = generatedSecretOf(t, s)
+	if _, ok := secret.Annotations[pendingOwnerAnnotation]; ok {
+		t.Errorf("expected the pending owner to be removed, got %v", secret.Annotations)
+	}
+	if len(secret.OwnerReferences) != 1 {
+		t.Fatalf("expected the generated Secret to have one owner, got %+v", secret.OwnerReferences)
+	}
+	owner := secret.OwnerReferences[0]
+	if owner.Kind != "Deployment" || owner.APIVersion != "apps/v1" || owner.Name != "app" || owner.UID != patched.UID {
+		t.Errorf("expected the generated Secret to be owned by the Deployment, got %+v", owner)
+	}
+}
+
+func TestGeneratedSecretOwnerOnUpdate(t *testing.T) {
+	s := newTestInjector(fakeProvider{"/app/password": {Value: "hunter2", Type: ssmtypes.ParameterTypeSecureString}})
+	s.EnableSecretRefs = true
+
+	deployment := newSecretRefDeployment()
+	deployment.UID = types.UID("0b7a3c1e-4d2f-4e8a-9c6b-5f1d2e3a4b5c")
+	if resp := admit(t, s, admissionv1.Update, deployment, &appsv1.Deployment{}); !resp.Allowed {
+		t.Fatalf("expected the Deployment to be allowed, got %v", resp.Result)
+	}
+
+	secret := generatedSecretOf(t, s)
+	if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].UID != deployment.UID {
+		t.Errorf("expected the generated Secret to be owned by the Deployment, got %+v", secret.OwnerReferences)
+	}
+	if _, ok := secret.Annotations[pendingOwnerAnnotation]; ok {
+		t.Errorf("expected the generated Secret to have no pending owner, got %v", secret.Annotations)
+	}
+}
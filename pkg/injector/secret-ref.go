@@ -0,0 +1,198 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	annotationPrefix = "ssm-injector.aedificans.com/"
+	// secretRefAnnotation opts a workload into injecting SecureString values through a
+	// generated Secret and secretKeyRef rather than writing them into the env var value.
+	secretRefAnnotation = annotationPrefix + "secret-ref"
+)
+
+var invalidSecretKeyChars = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+
+// generatedSecret holds the SecureString values of a workload which are materialized into a
+// Secret, along with the secretKeyRefs which need to be pointed at it once it is named.
+type generatedSecret struct {
+	baseName  string
+	namespace string
+	owner     *metav1.OwnerReference
+	// pendingOwner is the workload being created, which has no UID to be referenced by yet.
+	pendingOwner *metav1.OwnerReference
+	dryRun       bool
+	data         map[string]string
+	selectors    []*corev1.SecretKeySelector
+}
+
+// newGeneratedSecret returns the Secret for the object's SecureString values when secret-ref
+// mode is enabled for it through an annotation on the object or its pod template.
+func (s *SSMParameterInjector) newGeneratedSecret(
+	req admission.Request,
+	objectMeta *metav1.ObjectMeta,
+	templateMeta *metav1.ObjectMeta,
+) *generatedSecret {
+	if objectMeta.Annotations[secretRefAnnotation] != "true" &&
+		(templateMeta == nil || templateMeta.Annotations[secretRefAnnotation] != "true") {
+		return nil
+	}
+	if !s.EnableSecretRefs {
+		log.Log.Info("Secret-ref mode requested but not enabled, SecureString values will be injected in plaintext")
+		return nil
+	}
+
+	secret := &generatedSecret{
+		baseName:  objectMeta.Name,
+		namespace: req.Namespace,
+		dryRun:    req.DryRun != nil && *req.DryRun,
+		data:      make(map[string]string),
+	}
+
+	gvk := schema.GroupVersion{Group: req.Kind.Group, Version: req.Kind.Version}.WithKind(req.Kind.Kind)
+	switch {
+	case objectMeta.UID != "":
+		secret.owner = metav1.NewControllerRef(objectMeta, gvk)
+	case metav1.GetControllerOf(objectMeta) != nil:
+		secret.owner = metav1.GetControllerOf(objectMeta)
+	case objectMeta.Name != "":
+		// The workload is being created and is only assigned its UID once admitted, so the
+		// GeneratedSecretReconciler sets it as the owner once it exists.
+		secret.pendingOwner = metav1.NewControllerRef(objectMeta, gvk)
+	}
+	// Blocking the owner's deletion requires permissions on every workload kind's finalizers.
+	for _, owner := range []*metav1.OwnerReference{secret.owner, secret.pendingOwner} {
+		if owner != nil {
+			owner.BlockOwnerDeletion = nil
+		}
+	}
+
+	if secret.baseName == "" {
+		secret.baseName = strings.TrimSuffix(objectMeta.GenerateName, "-")
+	}
+	if secret.baseName == "" && secret.owner != nil {
+		secret.baseName = secret.owner.Name
+	}
+
+	return secret
+}
+
+// add stores the value under a key derived from the container and env var names, returning
+// the selector which the env var should use to reference it.
+func (g *generatedSecret) add(key string, value string) *corev1.SecretKeySelector {
+	key = invalidSecretKeyChars.ReplaceAllString(key, "_")
+	g.data[key] = value
+
+	selector := &corev1.SecretKeySelector{Key: key}
+	g.selectors = append(g.selectors, selector)
+	return selector
+}
+
+// name derives the Secret's name from the workload's name and a hash of its content, so that
+// every Pod of a ReplicaSet with the same values shares a single Secret.
+func (g *generatedSecret) name() string {
+	keys := make([]string, 0, len(g.data))
+	for key := range g.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	hash.Write([]byte(g.namespace))
+	for _, key := range keys {
+		fmt.Fprintf(hash, "\x00%s\x00%s", key, g.data[key])
+	}
+	suffix := "-ssm-" + hex.EncodeToString(hash.Sum(nil))[:10]
+
+	baseName := g.baseName
+	if maxLength := 253 - len(suffix); len(baseName) > maxLength {
+		baseName = strings.TrimSuffix(baseName[:maxLength], "-")
+	}
+	return baseName + suffix
+}
+
+// createGeneratedSecret names the generated Secret, points the collected secretKeyRefs at it
+// and creates it. Secrets are named by their content so an existing Secret is left as is.
+func (s *SSMParameterInjector) createGeneratedSecret(ctx context.Context, secret *generatedSecret) error {
+	if len(secret.data) == 0 {
+		return nil
+	}
+
+	name := secret.name()
+	for _, selector := range secret.selectors {
+		selector.Name = name
+	}
+
+	if secret.dryRun {
+		log.Log.WithValues("secretName", name).Info("Dry run, skipping creation of generated Secret")
+		return nil
+	}
+
+	secretObject := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: secret.namespace,
+			Labels: map[string]string{
+				managedByLabel: managedBy,
+			},
+		},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: secret.data,
+	}
+	switch {
+	case secret.owner != nil:
+		secretObject.OwnerReferences = []metav1.OwnerReference{*secret.owner}
+	case secret.pendingOwner != nil:
+		pendingOwner, err := json.Marshal(secret.pendingOwner)
+		if err != nil {
+			return fmt.Errorf("failed to marshal pending owner of generated Secret: %s", err)
+		}
+		secretObject.Annotations = map[string]string{pendingOwnerAnnotation: string(pendingOwner)}
+	default:
+		log.Log.WithValues("secretName", name).
+			Info("Workload has no name, UID or controller yet, generated Secret will not be garbage collected")
+	}
+
+	log.Log.WithValues("secretName", name).Info("Creating generated Secret for SecureString values")
+	err := s.Client.Create(ctx, secretObject)
+	if apierrors.IsAlreadyExists(err) {
+		log.Log.WithValues("secretName", name).V(1).Info("Generated Secret already exists")
+		return nil
+	}
+	if err != nil {
+		log.Log.WithValues("secretName", name).Error(err, "failed to create generated Secret")
+		return fmt.Errorf("failed to create generated Secret: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func (s *SSMParameterInjector) handleSecret(ctx context.Context, req admission.Request) admission.Response {
+	secret := &corev1.Secret{}
+
+	log.Log.V(1).Info("Decoding Secret from request")
+	err := s.Decoder.Decode(req, secret)
+	if err != nil {
+		log.Log.Error(err, "unable to decode Secret")
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	log.Log.WithValues("name", secret.Name, "namespace", secret.Namespace).
+		V(1).Info("Secret successfully decoded")
+
+	refs := &parameterReferences{}
+	collectObjectMeta(refs, &secret.ObjectMeta)
+	// The API server merges stringData into data before admission, so only data is seen here.
+	for key, value := range secret.Data {
+		if !utf8.Valid(value) {
+			continue
+		}
+		refs.add("Secret data", string(value), func(paramValue string) {
+			secret.Data[key] = []byte(paramValue)
+		})
+	}
+
+	wasModified, err := s.injectParameters(ctx, refs)
+	if err != nil {
+		return admission.Errored(errorCode(err), err)
+	}
+
+	if !wasModified {
+		log.Log.Info("No SSM parameters found")
+		return admission.Allowed("No modifications required")
+	}
+
+	secretJson, err := json.Marshal(secret)
+	if err != nil {
+		log.Log.Error(err, "unable to marshal modified Secret to JSON")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	log.Log.Info("Returning JSON patch for value injection(s)")
+	return admission.PatchResponseFromRaw(req.Object.Raw, secretJson).WithWarnings(refs.warnings...)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHandleSecretData(t *testing.T) {
+	s := newTestInjector(fakeProvider{"/app/password": {Value: "hunter2"}, "/app/user": {Value: "admin"}})
+	binary := []byte("\xff\xfe${ssm://app/password}")
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Data: map[string][]byte{
+			"password": []byte("ssm://app/password"),
+			"url":      []byte("postgres://${ssm://app/user}@db"),
+			"plain":    []byte("value"),
+			"binary":   binary,
+		},
+	}
+
+	patched := &corev1.Secret{}
+	resp := admit(t, s, admissionv1.Create, secret, patched)
+	if !resp.Allowed {
+		t.Fatalf("expected the Secret to be allowed, got %v", resp.Result)
+	}
+
+	expected := map[string]string{
+		"password": "hunter2",
+		"url":      "postgres://admin@db",
+		"plain":    "value",
+		"binary":   string(binary),
+	}
+	for key, value := range expected {
+		if string(patched.Data[key]) != value {
+			t.Errorf("expected data %s to be %q, got %q", key, value, patched.Data[key])
+		}
+	}
+}
@@ -38,14 +38,12 @@ func (s *SSMParameterInjector) handleServiceAccount(ctx context.Context, req adm
 	log.Log.WithValues("name", serviceAccount.Name, "namespace", serviceAccount.Namespace).
 		V(1).Info("ServiceAccount successfully decoded")
 
-	if serviceAccount.Annotations == nil {
-		log.Log.Info("No annotations present on the ServiceAccount")
-		return admission.Allowed("No modifications required")
-	}
+	refs := &parameterReferences{}
+	collectObjectMeta(refs, &serviceAccount.ObjectMeta)
 
-	wasModified, err := s.processAnnotations(ctx, serviceAccount.Annotations)
+	wasModified, err := s.injectParameters(ctx, refs)
 	if err != nil {
-		return admission.Errored(http.StatusInternalServerError, err)
+		return admission.Errored(errorCode(err), err)
 	}
 
 	if !wasModified {
@@ -60,5 +58,5 @@ func (s *SSMParameterInjector) handleServiceAccount(ctx context.Context, req adm
 	}
 
 	log.Log.Info("Returning JSON patch for value injection(s)")
-	return admission.PatchResponseFromRaw(req.Object.Raw, serviceAccountJson)
+	return admission.PatchResponseFromRaw(req.Object.Raw, serviceAccountJson).WithWarnings(refs.warnings...)
 }
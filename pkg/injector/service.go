@@ -0,0 +1,78 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func (s *SSMParameterInjector) handleService(ctx context.Context, req admission.Request) admission.Response {
+	service := &corev1.Service{}
+
+	log.Log.V(1).Info("Decoding Service from request")
+	err := s.Decoder.Decode(req, service)
+	if err != nil {
+		log.Log.Error(err, "unable to decode Service")
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	log.Log.WithValues("name", service.Name, "namespace", service.Namespace).
+		V(1).Info("Service successfully decoded")
+
+	refs := &parameterReferences{}
+	collectObjectMeta(refs, &service.ObjectMeta)
+	refs.addValidated("Service external name", service.Spec.ExternalName, validator(validation.IsDNS1123Subdomain),
+		func(paramValue string) {
+			service.Spec.ExternalName = paramValue
+		})
+	collectStringList(refs, "Service load balancer source range", &service.Spec.LoadBalancerSourceRanges, validateCIDR)
+
+	wasModified, err := s.injectParameters(ctx, refs)
+	if err != nil {
+		return admission.Errored(errorCode(err), err)
+	}
+
+	if !wasModified {
+		log.Log.Info("No SSM parameters found")
+		return admission.Allowed("No modifications required")
+	}
+
+	serviceJson, err := json.Marshal(service)
+	if err != nil {
+		log.Log.Error(err, "unable to marshal modified Service to JSON")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	log.Log.Info("Returning JSON patch for value injection(s)")
+	return admission.PatchResponseFromRaw(req.Object.Raw, serviceJson).WithWarnings(refs.warnings...)
+}
+
+// validateCIDR checks the value is a valid IPv4 or IPv6 CIDR, such as "10.0.0.0/16".
+func validateCIDR(value string) error {
+	if _, _, err := net.ParseCIDR(value); err != nil {
+		return fmt.Errorf("%q is not a valid CIDR", value)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newServiceTestInjector() *SSMParameterInjector {
+	return newTestInjector(fakeProvider{
+		"/app/db/host":      {Value: "db.example.com"},
+		"/app/owner":        {Value: "platform"},
+		"/app/office-cidr":  {Value: "192.0.2.0/24"},
+		"/app/vpn-cidrs":    {Value: "198.51.100.0/24, 2001:db8::/32", Type: ssmtypes.ParameterTypeStringList},
+		"/app/invalid-cidr": {Value: "192.0.2.1"},
+		"/app/invalid-host": {Value: "db_host.example.com"},
+	})
+}
+
+func TestHandleService(t *testing.T) {
+	s := newServiceTestInjector()
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "db",
+			Namespace:   "default",
+			Annotations: map[string]string{"owner": "ssm://app/owner"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:                     corev1.ServiceTypeExternalName,
+			ExternalName:             "ssm://app/db/host",
+			LoadBalancerSourceRanges: []string{"ssm://app/office-cidr", "ssm://app/vpn-cidrs", "203.0.113.0/24"},
+		},
+	}
+
+	patched := &corev1.Service{}
+	resp := admit(t, s, admissionv1.Create, service, patched)
+	if !resp.Allowed {
+		t.Fatalf("expected the Service to be allowed, got %v", resp.Result)
+	}
+
+	if owner := patched.Annotations["owner"]; owner != "platform" {
+		t.Errorf("expected the annotation to be resolved, got %q", owner)
+	}
+	if patched.Spec.ExternalName != "db.example.com" {
+		t.Errorf("expected the external name to be resolved, got %q", patched.Spec.ExternalName)
+	}
+	// A StringList parameter is expanded into a source range per item.
+	expected := []string{"192.0.2.0/24", "198.51.100.0/24", "2001:db8::/32", "203.0.113.0/24"}
+	if ranges := patched.Spec.LoadBalancerSourceRanges; !slices.Equal(ranges, expected) {
+		t.Errorf("expected source ranges %q, got %q", expected, ranges)
+	}
+}
+
+func TestHandleServiceInvalidValues(t *testing.T) {
+	s := newServiceTestInjector()
+
+	tests := []struct {
+		name string
+		spec corev1.ServiceSpec
+	}{
+		{
+			name: "external name",
+			spec: corev1.ServiceSpec{Type: corev1.ServiceTypeExternalName, ExternalName: "ssm://app/invalid-host"},
+		},
+		{
+			name: "source range",
+			spec: corev1.ServiceSpec{
+				Type:                     corev1.ServiceTypeLoadBalancer,
+				LoadBalancerSourceRanges: []string{"ssm://app/office-cidr", "ssm://app/invalid-cidr"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+				Spec:       test.spec,
+			}
+
+			resp := admit(t, s, admissionv1.Create, service, &corev1.Service{})
+			if resp.Allowed || resp.Result.Code != http.StatusBadRequest {
+				t.Errorf("expected the Service to be rejected as a bad request, got %v", resp.Result)
+			}
+		})
+	}
+}
+
+func TestHandleServiceOfAnotherGroup(t *testing.T) {
+	s := newServiceTestInjector()
+
+	// Without a field path rule for it, a Knative Service is left as it is rather than decoded
+	// as a core Service.
+	service := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "serving.knative.dev/v1",
+		"kind":       "Service",
+		"metadata":   map[string]any{"name": "db", "namespace": "default"},
+		"spec":       map[string]any{"externalName": "ssm://app/db/host"},
+	}}
+
+	resp := admit(t, s, admissionv1.Create, service, &unstructured.Unstructured{})
+	if !resp.Allowed || len(resp.Patches) > 0 {
+		t.Errorf("expected the Service to be allowed unmodified, got %v with %v", resp.Result, resp.Patches)
+	}
+}
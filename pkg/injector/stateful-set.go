@@ -0,0 +1,66 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func (s *SSMParameterInjector) handleStatefulSet(ctx context.Context, req admission.Request) admission.Response {
+	statefulSet := &appsv1.StatefulSet{}
+
+	log.Log.V(1).Info("Decoding StatefulSet from request")
+	err := s.Decoder.Decode(req, statefulSet)
+	if err != nil {
+		log.Log.Error(err, "unable to decode StatefulSet")
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	log.Log.WithValues("name", statefulSet.Name, "namespace", statefulSet.Namespace).
+		V(1).Info("StatefulSet successfully decoded")
+
+	refs := &parameterReferences{
+		secret: s.newGeneratedSecret(req, &statefulSet.ObjectMeta, &statefulSet.Spec.Template.ObjectMeta),
+	}
+	collectObjectMeta(refs, &statefulSet.ObjectMeta)
+	collectLabelSelector(refs, statefulSet.Spec.Selector)
+	collectPodTemplate(refs, &statefulSet.Spec.Template)
+
+	wasModified, err := s.injectParameters(ctx, refs)
+	if err != nil {
+		return admission.Errored(errorCode(err), err)
+	}
+
+	if !wasModified {
+		log.Log.Info("No SSM parameters found")
+		return admission.Allowed("No modifications required")
+	}
+
+	statefulSetJson, err := json.Marshal(statefulSet)
+	if err != nil {
+		log.Log.Error(err, "unable to marshal modified StatefulSet to JSON")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	log.Log.Info("Returning JSON patch for value injection(s)")
+	return admission.PatchResponseFromRaw(req.Object.Raw, statefulSetJson).WithWarnings(refs.warnings...)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package injector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// handleUnstructured searches resources of kinds without a dedicated handler for SSM Parameter
+// references in the fields selected by the matching FieldPathRules. Resources of kinds without
+// any configured field paths are allowed unmodified.
+func (s *SSMParameterInjector) handleUnstructured(ctx context.Context, req admission.Request) admission.Response {
+	fieldPaths := s.configuredFieldPaths(req)
+	if len(fieldPaths) == 0 {
+		log.Log.WithValues("kind", schema.GroupVersionKind(req.Kind).String()).Info("No field paths configured for Kind")
+		return admission.Allowed("No modifications required")
+	}
+
+	return s.injectUnstructured(ctx, req, fieldPaths)
+}
+
+// configuredFieldPaths returns the field paths of every FieldPathRule matching the request's kind.
+func (s *SSMParameterInjector) configuredFieldPaths(req admission.Request) []FieldPath {
+	var fieldPaths []FieldPath
+	for _, rule := range s.FieldPathRules {
+		if rule.matches(schema.GroupVersionKind(req.Kind)) {
+			fieldPaths = append(fieldPaths, rule.FieldPaths...)
+		}
+	}
+	return fieldPaths
+}
+
+// injectUnstructured searches the resource's annotations, labels and the fields selected by the
+// field paths for SSM Parameter references. The resource is decoded as unstructured content so
+// that every field is kept in the patched resource, including those unknown to the webhook.
+func (s *SSMParameterInjector) injectUnstructured(
+	ctx context.Context,
+	req admission.Request,
+	fieldPaths []FieldPath,
+) admission.Response {
+	gvk := schema.GroupVersionKind(req.Kind)
+
+	object := &unstructured.Unstructured{}
+
+	log.Log.V(1).Info("Decoding " + gvk.Kind + " from request")
+	err := s.Decoder.Decode(req, object)
+	if err != nil {
+		log.Log.Error(err, "unable to decode "+gvk.Kind)
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	log.Log.WithValues("name", object.GetName(), "namespace", object.GetNamespace()).
+		V(1).Info(gvk.Kind + " successfully decoded")
+
+	refs := &parameterReferences{}
+	collectUnstructuredMetadata(refs, object.Object)
+	for _, fieldPath := range fieldPaths {
+		collectFieldPath(refs, gvk.Kind, object.Object, fieldPath)
+	}
+
+	wasModified, err := s.injectParameters(ctx, refs)
+	if err != nil {
+		return admission.Errored(errorCode(err), err)
+	}
+
+	if !wasModified {
+		log.Log.Info("No SSM parameters found")
+		return admission.Allowed("No modifications required")
+	}
+
+	objectJson, err := json.Marshal(object)
+	if err != nil {
+		log.Log.Error(err, "unable to marshal modified "+gvk.Kind+" to JSON")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	log.Log.Info("Returning JSON patch for value injection(s)")
+	return admission.PatchResponseFromRaw(req.Object.Raw, objectJson).WithWarnings(refs.warnings...)
+}
+
+// collectUnstructuredMetadata searches the annotations and labels of unstructured content for
+// SSM Parameter references, in the same way as collectObjectMeta.
+func collectUnstructuredMetadata(refs *parameterReferences, object map[string]interface{}) {
+	metadata, _ := object["metadata"].(map[string]interface{})
+
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	for key, value := range annotations {
+		if value, ok := value.(string); ok && !isSystemAnnotation(key) {
+			refs.add("annotation "+key, value, func(paramValue string) {
+				annotations[key] = paramValue
+			})
+		}
+	}
+
+	labels, _ := metadata["labels"].(map[string]interface{})
+	for key, value := range labels {
+		if value, ok := value.(string); ok {
+			refs.addValidated("label "+key, value, validateLabelValue, func(paramValue string) {
+				labels[key] = paramValue
+			})
+		}
+	}
+}
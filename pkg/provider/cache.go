@@ -0,0 +1,169 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	cacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ssm_param_injector_cache_hits_total",
+		Help: "Total number of parameter values served from the cache.",
+	})
+	cacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ssm_param_injector_cache_misses_total",
+		Help: "Total number of parameter values not found in the cache.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(cacheHits, cacheMisses)
+}
+
+// CachingProvider holds parameter values retrieved from another Provider for a limited time
+// and coalesces concurrent retrievals of the same parameter into a single call.
+type CachingProvider struct {
+	provider Provider
+	ttl      time.Duration
+	values   *cache.LRUExpireCache
+	// paths holds the parameters retrieved beneath each path.
+	paths *cache.LRUExpireCache
+
+	mu       sync.Mutex
+	inFlight map[string]*parameterCall
+}
+
+// parameterCall is an in-flight retrieval of a single SSM Parameter which other admission
+// requests can wait on rather than retrieving the same parameter themselves.
+type parameterCall struct {
+	done  chan struct{}
+	value Parameter
+	found bool
+	err   error
+}
+
+// NewCachingProvider creates a cache in front of the provider, holding at most maxSize
+// parameter values for the given TTL.
+func NewCachingProvider(provider Provider, ttl time.Duration, maxSize int) *CachingProvider {
+	return &CachingProvider{
+		provider: provider,
+		ttl:      ttl,
+		values:   cache.NewLRUExpireCache(maxSize),
+		paths:    cache.NewLRUExpireCache(maxSize),
+		inFlight: make(map[string]*parameterCall),
+	}
+}
+
+// GetParameters returns the values of the named parameters, serving what it can from the
+// cache, waiting on retrievals already in flight, and passing the remaining names to the
+// underlying provider as a single batch. Parameters which do not exist are not cached.
+func (c *CachingProvider) GetParameters(ctx context.Context, paramNames []string) (map[string]Parameter, error) {
+	paramValues := make(map[string]Parameter, len(paramNames))
+	waiting := make(map[string]*parameterCall)
+	owned := make(map[string]*parameterCall)
+
+	c.mu.Lock()
+	for _, paramName := range paramNames {
+		if value, ok := c.values.Get(paramName); ok {
+			cacheHits.Inc()
+			paramValues[paramName] = value.(Parameter)
+			continue
+		}
+		cacheMisses.Inc()
+		if call, ok := c.inFlight[paramName]; ok {
+			waiting[paramName] = call
+			continue
+		}
+		call := &parameterCall{done: make(chan struct{})}
+		c.inFlight[paramName] = call
+		owned[paramName] = call
+	}
+	c.mu.Unlock()
+
+	log.Log.WithValues("cached", len(paramValues), "waiting", len(waiting), "retrieving", len(owned)).
+		V(1).Info("Checked SSM Parameter cache")
+
+	if len(owned) > 0 {
+		ownedNames := make([]string, 0, len(owned))
+		for paramName := range owned {
+			ownedNames = append(ownedNames, paramName)
+		}
+		retrievedValues, err := c.provider.GetParameters(ctx, ownedNames)
+
+		c.mu.Lock()
+		for paramName, call := range owned {
+			call.value, call.found = retrievedValues[paramName]
+			call.err = err
+			if err == nil && call.found {
+				c.values.Add(paramName, call.value, c.ttl)
+			}
+			delete(c.inFlight, paramName)
+			close(call.done)
+		}
+		c.mu.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+		for paramName, value := range retrievedValues {
+			paramValues[paramName] = value
+		}
+	}
+
+	for paramName, call := range waiting {
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if call.err != nil {
+			return nil, call.err
+		}
+		if call.found {
+			paramValues[paramName] = call.value
+		}
+	}
+
+	return paramValues, nil
+}
+
+// GetParametersByPath returns the parameters beneath the path, serving them from the cache
+// when the path was retrieved within the TTL. The returned values are shared and must not be
+// modified.
+func (c *CachingProvider) GetParametersByPath(ctx context.Context, path string) (map[string]Parameter, error) {
+	if paramValues, ok := c.paths.Get(path); ok {
+		cacheHits.Inc()
+		return paramValues.(map[string]Parameter), nil
+	}
+	cacheMisses.Inc()
+
+	paramValues, err := c.provider.GetParametersByPath(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	c.paths.Add(path, paramValues, c.ttl)
+
+	return paramValues, nil
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingProvider serves fixed parameters, counting the names and paths it is asked for and
+// blocking each retrieval until release is closed, if set.
+type countingProvider struct {
+	parameters map[string]Parameter
+	err        error
+	release    chan struct{}
+
+	mu    sync.Mutex
+	names map[string]int
+	paths map[string]int
+}
+
+func newCountingProvider(parameters map[string]Parameter) *countingProvider {
+	return &countingProvider{parameters: parameters, names: map[string]int{}, paths: map[string]int{}}
+}
+
+func (p *countingProvider) GetParameters(_ context.Context, paramNames []string) (map[string]Parameter, error) {
+	p.mu.Lock()
+	for _, paramName := range paramNames {
+		p.names[paramName]++
+	}
+	p.mu.Unlock()
+	if p.release != nil {
+		<-p.release
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	paramValues := map[string]Parameter{}
+	for _, paramName := range paramNames {
+		if value, ok := p.parameters[paramName]; ok {
+			paramValues[paramName] = value
+		}
+	}
+	return paramValues, nil
+}
+
+func (p *countingProvider) GetParametersByPath(_ context.Context, path string) (map[string]Parameter, error) {
+	p.mu.Lock()
+	p.paths[path]++
+	p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	return map[string]Parameter{path + "/name": p.parameters[path+"/name"]}, nil
+}
+
+func (p *countingProvider) count(paramName string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.names[paramName]
+}
+
+func TestCachingProviderGetParameters(t *testing.T) {
+	underlying := newCountingProvider(map[string]Parameter{"/app/a": {Value: "a"}, "/app/b": {Value: "b"}})
+	c := NewCachingProvider(underlying, time.Minute, 10)
+
+	for range 2 {
+		paramValues, err := c.GetParameters(context.Background(), []string{"/app/a", "/app/b", "/app/missing"})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(paramValues) != 2 || paramValues["/app/a"].Value != "a" || paramValues["/app/b"].Value != "b" {
+			t.Errorf("expected /app/a and /app/b, got %+v", paramValues)
+		}
+	}
+
+	if count := underlying.count("/app/a"); count != 1 {
+		t.Errorf("expected /app/a to be retrieved once, got %d", count)
+	}
+	// Parameters which do not exist are not cached, so that they are found once created.
+	if count := underlying.count("/app/missing"); count != 2 {
+		t.Errorf("expected /app/missing to be retrieved twice, got %d", count)
+	}
+}
+
+func TestCachingProviderExpiry(t *testing.T) {
+	underlying := newCountingProvider(map[string]Parameter{"/app/a": {Value: "a"}})
+	c := NewCachingProvider(underlying, time.Millisecond, 10)
+
+	for range 2 {
+		if _, err := c.GetParameters(context.Background(), []string{"/app/a"}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if count := underlying.count("/app/a"); count != 2 {
+		t.Errorf("expected /app/a to be retrieved again once expired, got %d", count)
+	}
+}
+
+func TestCachingProviderErrors(t *testing.T) {
+	underlying := newCountingProvider(map[string]Parameter{"/app/a": {Value: "a"}})
+	underlying.err = errors.New("throttled")
+	c := NewCachingProvider(underlying, time.Minute, 10)
+
+	if _, err := c.GetParameters(context.Background(), []string{"/app/a"}); err == nil {
+		t.Fatal("expected the provider's error")
+	}
+
+	underlying.err = nil
+	paramValues, err := c.GetParameters(context.Background(), []string{"/app/a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if paramValues["/app/a"].Value != "a" {
+		t.Errorf("expected /app/a to be retrieved after the error, got %+v", paramValues)
+	}
+}
+
+func TestCachingProviderCoalescing(t *testing.T) {
+	underlying := newCountingProvider(map[string]Parameter{"/app/a": {Value: "a"}})
+	underlying.release = make(chan struct{})
+	c := NewCachingProvider(underlying, time.Minute, 10)
+
+	var wg sync.WaitGroup
+	results := make([]map[string]Parameter, 5)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = c.GetParameters(context.Background(), []string{"/app/a"})
+		}()
+	}
+
+	// Every request is waiting on the first retrieval once it has started and the others are
+	// in flight, at which point it is released.
+	for underlying.count("/app/a") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(underlying.release)
+	wg.Wait()
+
+	if count := underlying.count("/app/a"); count != 1 {
+		t.Errorf("expected concurrent retrievals of /app/a to be coalesced, got %d", count)
+	}
+	for _, result := range results {
+		if result["/app/a"].Value != "a" {
+			t.Errorf("expected every request to receive /app/a, got %+v", result)
+		}
+	}
+}
+
+func TestCachingProviderGetParametersByPath(t *testing.T) {
+	underlying := newCountingProvider(map[string]Parameter{"/app/name": {Value: "a"}})
+	c := NewCachingProvider(underlying, time.Minute, 10)
+
+	for range 2 {
+		paramValues, err := c.GetParametersByPath(context.Background(), "/app")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if paramValues["/app/name"].Value != "a" {
+			t.Errorf("expected /app/name, got %+v", paramValues)
+		}
+	}
+	if count := underlying.paths["/app"]; count != 1 {
+		t.Errorf("expected /app to be retrieved once, got %d", count)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+// FileProvider retrieves parameter values from a YAML document mapping parameter names to
+// values, such as a mounted ConfigMap, so that the webhook can run without AWS. A value is
+// either a string or an object with "value" and "type" fields:
+//
+//	/app/db/host: db.local
+//	/app/db/password:
+//	  value: hunter2
+//	  type: SecureString
+//
+// The document is re-read whenever the file changes.
+type FileProvider struct {
+	path string
+
+	mu         sync.Mutex
+	modTime    time.Time
+	parameters map[string]Parameter
+}
+
+// fileParameter is a parameter entry of the document, which may be given as a plain value.
+type fileParameter struct {
+	Value string              `json:"value"`
+	Type  types.ParameterType `json:"type,omitempty"`
+}
+
+func (p *fileParameter) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte("{")) {
+		type entry fileParameter
+		return json.Unmarshal(data, (*entry)(p))
+	}
+	if err := json.Unmarshal(data, &p.Value); err != nil {
+		// Numbers and booleans are used as written in the document.
+		p.Value = string(data)
+	}
+	return nil
+}
+
+// NewFileProvider creates a provider reading the YAML document at path, which must exist.
+func NewFileProvider(path string) (*FileProvider, error) {
+	p := &FileProvider{path: path}
+	if _, err := p.load(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetParameters returns the values of the named parameters from the document, which are
+// matched with or without a leading "/" as they are by SSM. A name with a selector which is
+// not in the document falls back to the name without its selector.
+func (p *FileProvider) GetParameters(_ context.Context, paramNames []string) (map[string]Parameter, error) {
+	parameters, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+
+	paramValues := make(map[string]Parameter, len(paramNames))
+	for _, paramName := range paramNames {
+		name := normalizeParameterName(paramName)
+		value, ok := parameters[name]
+		if !ok {
+			if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+				value, ok = parameters[name[:i]]
+			}
+		}
+		if ok {
+			paramValues[paramName] = value
+		}
+	}
+
+	return paramValues, nil
+}
+
+// GetParametersByPath returns the values of every parameter in the document whose name is
+// beneath the path, other than the versions and labels of parameters.
+func (p *FileProvider) GetParametersByPath(_ context.Context, path string) (map[string]Parameter, error) {
+	parameters, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := normalizeParameterName(strings.TrimSuffix(path, "/") + "/")
+	paramValues := map[string]Parameter{}
+	for name, value := range parameters {
+		// Versions and labels of parameters are not parameters of their own.
+		if strings.LastIndex(name, ":") > strings.LastIndex(name, "/") {
+			continue
+		}
+		if strings.HasPrefix(name, prefix) {
+			// Names are returned fully qualified, as they are by SSM.
+			paramValues["/"+name] = value
+		}
+	}
+
+	return paramValues, nil
+}
+
+// load returns the parameters of the document, re-reading it if it has changed.
+func (p *FileProvider) load() (map[string]Parameter, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parameters file: %s", err)
+	}
+	if p.parameters != nil && info.ModTime().Equal(p.modTime) {
+		return p.parameters, nil
+	}
+
+	log.Log.WithValues("path", p.path).Info("Loading parameters file")
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parameters file: %s", err)
+	}
+
+	entries := map[string]fileParameter{}
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse parameters file: %s", err)
+	}
+
+	parameters := make(map[string]Parameter, len(entries))
+	for name, entry := range entries {
+		if entry.Type == "" {
+			entry.Type = types.ParameterTypeString
+		}
+		parameters[normalizeParameterName(name)] = Parameter{Value: entry.Value, Type: entry.Type}
+	}
+
+	p.modTime, p.parameters = info.ModTime(), parameters
+	return parameters, nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+const testParametersFile = `
+/app/db/host: db.local
+app/db/port: 5432
+/app/db/password:
+  value: hunter2
+  type: SecureString
+/app/db/user: admin
+"/app/db/user:2": legacy
+/app/flags/beta: true
+/other/name: other
+`
+
+func newTestFileProvider(t *testing.T, content string) (*FileProvider, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "parameters.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("unable to write parameters file: %s", err)
+	}
+	p, err := NewFileProvider(path)
+	if err != nil {
+		t.Fatalf("unable to create file provider: %s", err)
+	}
+	return p, path
+}
+
+func TestFileProviderGetParameters(t *testing.T) {
+	p, _ := newTestFileProvider(t, testParametersFile)
+
+	tests := []struct {
+		name     string
+		expected *Parameter
+	}{
+		{name: "/app/db/host", expected: &Parameter{Value: "db.local", Type: types.ParameterTypeString}},
+		{name: "app/db/host", expected: &Parameter{Value: "db.local", Type: types.ParameterTypeString}},
+		{name: "/app/db/port", expected: &Parameter{Value: "5432", Type: types.ParameterTypeString}},
+		{name: "app/db/port", expected: &Parameter{Value: "5432", Type: types.ParameterTypeString}},
+		{name: "/app/db/password", expected: &Parameter{Value: "hunter2", Type: types.ParameterTypeSecureString}},
+		{name: "/app/db/user:2", expected: &Parameter{Value: "legacy", Type: types.ParameterTypeString}},
+		{name: "app/db/user:2", expected: &Parameter{Value: "legacy", Type: types.ParameterTypeString}},
+		{name: "/app/db/user:prod", expected: &Parameter{Value: "admin", Type: types.ParameterTypeString}},
+		{name: "/app/flags/beta", expected: &Parameter{Value: "true", Type: types.ParameterTypeString}},
+		{name: "/app/db/missing"},
+		{name: "/app/db"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paramValues, err := p.GetParameters(context.Background(), []string{test.name})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			value, found := paramValues[test.name]
+			switch {
+			case test.expected == nil && found:
+				t.Errorf("expected no parameter, got %+v", value)
+			case test.expected != nil && !found:
+				t.Errorf("expected %+v, got no parameter", *test.expected)
+			case test.expected != nil && value != *test.expected:
+				t.Errorf("expected %+v, got %+v", *test.expected, value)
+			}
+		})
+	}
+}
+
+func TestFileProviderGetParametersByPath(t *testing.T) {
+	p, _ := newTestFileProvider(t, testParametersFile)
+
+	expected := []string{"/app/db/host", "/app/db/password", "/app/db/port", "/app/db/user"}
+	for _, path := range []string{"/app/db", "/app/db/", "app/db", "app/db/"} {
+		t.Run(path, func(t *testing.T) {
+			paramValues, err := p.GetParametersByPath(context.Background(), path)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			var names []string
+			for name := range paramValues {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, expected) {
+				t.Errorf("expected %v, got %v", expected, names)
+			}
+		})
+	}
+}
+
+func TestFileProviderReload(t *testing.T) {
+	p, path := newTestFileProvider(t, "/app/db/host: db.local\n")
+
+	if err := os.WriteFile(path, []byte("/app/db/host: db.example.com\n"), 0o600); err != nil {
+		t.Fatalf("unable to write parameters file: %s", err)
+	}
+	// The file is re-read when its modification time changes.
+	modTime := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("unable to touch parameters file: %s", err)
+	}
+
+	paramValues, err := p.GetParameters(context.Background(), []string{"/app/db/host"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if value := paramValues["/app/db/host"].Value; value != "db.example.com" {
+		t.Errorf("expected the reloaded value, got %q", value)
+	}
+}
+
+func TestNewFileProviderErrors(t *testing.T) {
+	if _, err := NewFileProvider(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+
+	path := filepath.Join(t.TempDir(), "parameters.yaml")
+	if err := os.WriteFile(path, []byte("- not\n- a map\n"), 0o600); err != nil {
+		t.Fatalf("unable to write parameters file: %s", err)
+	}
+	if _, err := NewFileProvider(path); err == nil {
+		t.Error("expected an error for a document which is not a map")
+	}
+}
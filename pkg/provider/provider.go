@@ -0,0 +1,44 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// Parameter is the retrieved value of a parameter along with the parameter's type.
+type Parameter struct {
+	Value string
+	Type  types.ParameterType
+}
+
+func (p Parameter) IsSecure() bool {
+	return p.Type == types.ParameterTypeSecureString
+}
+
+// Provider retrieves parameter values for injection.
+type Provider interface {
+	// GetParameters returns the values of the named parameters, each of which may include a
+	// ":<version>" or ":<label>" selector. Parameters which do not exist are omitted from the
+	// returned values, while a failure to retrieve them is an error.
+	GetParameters(ctx context.Context, names []string) (map[string]Parameter, error)
+	// GetParametersByPath returns the values of every parameter beneath the path, including
+	// those in nested paths, keyed by their full names.
+	GetParametersByPath(ctx context.Context, path string) (map[string]Parameter, error)
+}
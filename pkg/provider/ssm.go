@@ -0,0 +1,134 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ssmGetParametersLimit is the maximum number of names accepted by a single GetParameters call.
+const ssmGetParametersLimit = 10
+
+// SSMClient is the part of the SSM API used by the SSMProvider, which *ssm.Client implements.
+type SSMClient interface {
+	ssm.GetParametersByPathAPIClient
+	GetParameters(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (
+		*ssm.GetParametersOutput, error)
+}
+
+// SSMProvider retrieves parameter values from AWS SSM Parameter Store.
+type SSMProvider struct {
+	Client SSMClient
+}
+
+// GetParameters retrieves the values of the named SSM Parameters, requesting them in batches
+// of up to ssmGetParametersLimit names per call.
+func (p *SSMProvider) GetParameters(ctx context.Context, paramNames []string) (map[string]Parameter, error) {
+	WithDecryption := true
+	retrievedValues := make(map[string]Parameter, len(paramNames))
+
+	for start := 0; start < len(paramNames); start += ssmGetParametersLimit {
+		batch := paramNames[start:min(start+ssmGetParametersLimit, len(paramNames))]
+		ssmRequestInput := &ssm.GetParametersInput{
+			Names:          batch,
+			WithDecryption: &WithDecryption,
+		}
+
+		log.Log.WithValues("paramNames", batch).V(1).Info("Retrieving SSM Parameter values")
+		ssmResponse, err := p.Client.GetParameters(ctx, ssmRequestInput)
+		if err != nil {
+			log.Log.WithValues("paramNames", batch).Error(err, "failed to retrieve SSM parameters")
+			return nil, fmt.Errorf("failed to retrieve SSM parameters: %s", err)
+		}
+
+		if len(ssmResponse.InvalidParameters) > 0 {
+			log.Log.WithValues("paramNames", ssmResponse.InvalidParameters).V(1).Info("SSM parameters not found")
+		}
+
+		for _, param := range ssmResponse.Parameters {
+			log.Log.WithValues("paramName", *param.Name, "paramValue", *param.Value).
+				V(2).Info("SSM Parameter retrieved value")
+			selector := ""
+			if param.Selector != nil && *param.Selector != "" {
+				selector = ":" + strings.TrimPrefix(*param.Selector, ":")
+			}
+			value := Parameter{
+				Value: *param.Value,
+				Type:  param.Type,
+			}
+			// Parameters requested by ARN may be returned under their name, so store both.
+			retrievedValues[normalizeParameterName(*param.Name+selector)] = value
+			if param.ARN != nil {
+				retrievedValues[*param.ARN+selector] = value
+			}
+		}
+	}
+
+	paramValues := make(map[string]Parameter, len(paramNames))
+	for _, paramName := range paramNames {
+		if value, ok := retrievedValues[normalizeParameterName(paramName)]; ok {
+			paramValues[paramName] = value
+		}
+	}
+
+	log.Log.V(1).Info("Returning retrieved SSM Parameter values")
+	return paramValues, nil
+}
+
+// normalizeParameterName strips the leading slash of hierarchical names so that a requested
+// name can be matched to the fully qualified name returned by SSM.
+func normalizeParameterName(paramName string) string {
+	return strings.TrimPrefix(paramName, "/")
+}
+
+// GetParametersByPath retrieves the values of every SSM Parameter beneath the path, following
+// each page of results.
+func (p *SSMProvider) GetParametersByPath(ctx context.Context, path string) (map[string]Parameter, error) {
+	WithDecryption := true
+	Recursive := true
+	paginator := ssm.NewGetParametersByPathPaginator(p.Client, &ssm.GetParametersByPathInput{
+		Path:           &path,
+		Recursive:      &Recursive,
+		WithDecryption: &WithDecryption,
+	})
+
+	paramValues := map[string]Parameter{}
+	for paginator.HasMorePages() {
+		log.Log.WithValues("path", path).V(1).Info("Retrieving SSM Parameter values by path")
+		ssmResponse, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Log.WithValues("path", path).Error(err, "failed to retrieve SSM parameters by path")
+			return nil, fmt.Errorf("failed to retrieve SSM parameters by path %s: %s", path, err)
+		}
+
+		for _, param := range ssmResponse.Parameters {
+			log.Log.WithValues("paramName", *param.Name, "paramValue", *param.Value).
+				V(2).Info("SSM Parameter retrieved value")
+			paramValues[*param.Name] = Parameter{
+				Value: *param.Value,
+				Type:  param.Type,
+			}
+		}
+	}
+
+	return paramValues, nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// stubSSMClient serves the parameters it holds, keyed by their fully qualified names, recording
+// the names requested by each GetParameters call.
+type stubSSMClient struct {
+	parameters map[string]types.Parameter
+	batches    [][]string
+}
+
+func (c *stubSSMClient) GetParameters(_ context.Context, params *ssm.GetParametersInput, _ ...func(*ssm.Options)) (
+	*ssm.GetParametersOutput, error) {
+	if len(params.Names) > ssmGetParametersLimit {
+		return nil, fmt.Errorf("ValidationException: %d names exceeds the limit", len(params.Names))
+	}
+	c.batches = append(c.batches, params.Names)
+
+	output := &ssm.GetParametersOutput{}
+	for _, name := range params.Names {
+		if param, ok := c.parameters["/"+normalizeParameterName(name)]; ok {
+			output.Parameters = append(output.Parameters, param)
+		} else {
+			output.InvalidParameters = append(output.InvalidParameters, name)
+		}
+	}
+	return output, nil
+}
+
+func (c *stubSSMClient) GetParametersByPath(_ context.Context, params *ssm.GetParametersByPathInput,
+	_ ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	return &ssm.GetParametersByPathOutput{}, nil
+}
+
+func TestSSMProviderGetParametersBatches(t *testing.T) {
+	client := &stubSSMClient{parameters: map[string]types.Parameter{}}
+	var names []string
+	for i := range 23 {
+		name := fmt.Sprintf("/app/param%d", i)
+		names = append(names, name)
+		client.parameters[name] = types.Parameter{
+			Name:  aws.String(name),
+			Value: aws.String(fmt.Sprint(i)),
+			Type:  types.ParameterTypeString,
+		}
+	}
+	// Names are requested as written, with or without their leading "/".
+	names = append(names, "app/param0", "/app/missing", "app/missing")
+
+	p := &SSMProvider{Client: client}
+	paramValues, err := p.GetParameters(context.Background(), names)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(client.batches) != 3 {
+		t.Fatalf("expected 26 names to be requested in 3 batches, got %d", len(client.batches))
+	}
+	for i, batch := range client.batches {
+		expected := names[i*ssmGetParametersLimit : min((i+1)*ssmGetParametersLimit, len(names))]
+		if !slices.Equal(batch, expected) {
+			t.Errorf("expected batch %d to be %v, got %v", i, expected, batch)
+		}
+	}
+
+	for i := range 23 {
+		name := fmt.Sprintf("/app/param%d", i)
+		if value := paramValues[name].Value; value != fmt.Sprint(i) {
+			t.Errorf("expected %s to be %d, got %q", name, i, value)
+		}
+	}
+	if value := paramValues["app/param0"].Value; value != "0" {
+		t.Errorf("expected app/param0 to be 0, got %q", value)
+	}
+	// Invalid parameters are reported as missing rather than as an error.
+	for _, name := range []string{"/app/missing", "app/missing"} {
+		if value, found := paramValues[name]; found {
+			t.Errorf("expected %s to be missing, got %+v", name, value)
+		}
+	}
+	if len(paramValues) != 24 {
+		t.Errorf("expected 24 parameters, got %d", len(paramValues))
+	}
+}
+
+func TestSSMProviderGetParametersError(t *testing.T) {
+	p := &SSMProvider{Client: &failingSSMClient{}}
+	if _, err := p.GetParameters(context.Background(), []string{"/app/a"}); err == nil {
+		t.Error("expected the client's error")
+	}
+}
+
+// failingSSMClient fails every request, as when access is denied or the request is throttled.
+type failingSSMClient struct {
+	stubSSMClient
+}
+
+func (c *failingSSMClient) GetParameters(context.Context, *ssm.GetParametersInput, ...func(*ssm.Options)) (
+	*ssm.GetParametersOutput, error) {
+	return nil, fmt.Errorf("ThrottlingException: Rate exceeded")
+}
@@ -20,6 +20,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 )
 
 func GetEnvBool(key string, defaultValue bool) bool {
@@ -36,6 +37,20 @@ func GetEnvBool(key string, defaultValue bool) bool {
 	return value
 }
 
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	envVarValue := os.Getenv(key)
+	if envVarValue == "" {
+		return defaultValue
+	}
+
+	value, err := time.ParseDuration(envVarValue)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return value
+}
+
 func GetEnvInt(key string, defaultValue int) int {
 	envVarValue := os.Getenv(key)
 	if envVarValue == "" {